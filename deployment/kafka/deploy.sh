@@ -71,8 +71,6 @@ kubectl wait --for=condition=ready pod --all -n "$NAMESPACE" --timeout=120s
 success "Kafka Cluster is ready"
 sleep_progress 10 "Sleeping before checking pods..."
 
-kubectl apply -f "$YAML_DIR/kafka-topic.yaml" -n $NAMESPACE
-
 echo
 banner "port forwarding rabbitmq svc, access rabbitmq broker at localhost:19092"
 kubectl port-forward pod/kafka-cluster-dual-role-0 19092:9094 -n kafka
\ No newline at end of file
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"producer/kafka"
+	"producer/store"
+	"producer/streams"
+	"strings"
+	"time"
+)
+
+// countsCommand runs the "counts" command, a streams pipeline that counts
+// messages per key and window of the usernames topic.
+func countsCommand(ctx context.Context, args []string) error {
+	var (
+		brokers string
+		window  time.Duration
+		advance time.Duration
+		db      string
+	)
+	fs := flag.NewFlagSet("counts", flag.ExitOnError)
+	fs.StringVar(&brokers, "brokers", url, "comma separated broker addresses")
+	fs.DurationVar(&window, "window", time.Minute, "window size")
+	fs.DurationVar(&advance, "advance", 0, "window advance for hopping windows, 0 for tumbling")
+	fs.StringVar(&db, "db", "", "keep window state in this file instead of in memory")
+	fs.Parse(args)
+
+	var st store.Store = store.NewMemory()
+	if db != "" {
+		var err error
+		if st, err = store.OpenDisk(db); err != nil {
+			return err
+		}
+	}
+	defer st.Close()
+
+	w := streams.Tumbling(window)
+	if advance > 0 {
+		w = streams.Hopping(window, advance)
+	}
+	addrs := strings.Split(brokers, ",")
+	return streams.From(
+		kafka.WithBrokers(addrs...),
+		kafka.WithTopic(topic),
+		kafka.WithGroup(topic+"-counts"),
+	).
+		Filter(func(m kafka.Message) bool { return m.Key != nil }).
+		GroupByKey().
+		WindowedBy(w).
+		Count(st).
+		To(
+			kafka.WithBrokers(addrs...),
+			kafka.WithTopic(topic+".counts"),
+		).
+		Run(ctx)
+}
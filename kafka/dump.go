@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"producer/dump"
+	"producer/kafka"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportCommand runs the "export" command, which dumps a topic or a range
+// of it to a file.
+func exportCommand(ctx context.Context, args []string) error {
+	var (
+		brokers    string
+		topicN     string
+		out        string
+		format     string
+		partitions string
+		fromTime   string
+		toTime     string
+		rng        kafka.ScanRange
+	)
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.StringVar(&brokers, "brokers", url, "comma separated broker addresses")
+	fs.StringVar(&topicN, "topic", topic, "topic to export")
+	fs.StringVar(&out, "o", "-", "file to write, - for stdout")
+	fs.StringVar(&format, "format", string(dump.JSONLines), "jsonl or binary")
+	fs.StringVar(&partitions, "partitions", "", "comma separated partitions, all if empty")
+	fs.Int64Var(&rng.FromOffset, "from-offset", 0, "first offset to export")
+	fs.Int64Var(&rng.ToOffset, "to-offset", 0, "export offsets below this one, 0 for no limit")
+	fs.StringVar(&fromTime, "from-time", "", "export records from this RFC3339 time on")
+	fs.StringVar(&toTime, "to-time", "", "export records before this RFC3339 time")
+	fs.Parse(args)
+
+	f, err := dump.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	if rng.Partitions, err = parseInts(partitions); err != nil {
+		return err
+	}
+	if rng.FromTime, err = parseTime(fromTime); err != nil {
+		return err
+	}
+	if rng.ToTime, err = parseTime(toTime); err != nil {
+		return err
+	}
+
+	var dst io.Writer = os.Stdout
+	if out != "-" {
+		file, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		dst = file
+	}
+	w, err := dump.NewWriter(dst, f)
+	if err != nil {
+		return err
+	}
+
+	n, err := dump.Export(ctx, w, rng,
+		kafka.WithBrokers(strings.Split(brokers, ",")...),
+		kafka.WithTopic(topicN),
+	)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %v records of %v\n", n, topicN)
+	return nil
+}
+
+// importCommand runs the "import" command, which produces the records of
+// an export file to a topic.
+func importCommand(ctx context.Context, args []string) error {
+	var (
+		brokers        string
+		topicN         string
+		in             string
+		partitioner    string
+		keepPartitions bool
+		create         bool
+	)
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.StringVar(&brokers, "brokers", url, "comma separated broker addresses")
+	fs.StringVar(&topicN, "topic", "", "topic to import into")
+	fs.StringVar(&in, "i", "-", "export file to read, - for stdin; the format is detected")
+	fs.BoolVar(&keepPartitions, "keep-partitions", false, "write records to the partition number they were exported from")
+	fs.StringVar(&partitioner, "partitioner", string(kafka.PartitionerMurmur2), "key partitioner used without -keep-partitions")
+	fs.BoolVar(&create, "create", false, "create the topic if it doesn't exist")
+	fs.Parse(args)
+
+	if topicN == "" {
+		return errors.New("usage: producer import -topic TOPIC [-i FILE] [-keep-partitions] [-partitioner P]")
+	}
+	p, err := kafka.ParsePartitioner(partitioner)
+	if err != nil {
+		return err
+	}
+
+	var src io.Reader = os.Stdin
+	if in != "-" {
+		file, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		src = file
+	}
+	r, err := dump.NewReader(src)
+	if err != nil {
+		return err
+	}
+
+	opts := []kafka.Option{
+		kafka.WithBrokers(strings.Split(brokers, ",")...),
+		kafka.WithTopic(topicN),
+		kafka.WithPartitioner(p),
+	}
+	if create {
+		admin, err := kafka.NewAdmin(opts...)
+		if err != nil {
+			return err
+		}
+		err = admin.EnsureTopic(ctx, kafka.TopicSpec{Name: topicN})
+		admin.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	producer, err := kafka.NewProducer(opts...)
+	if err != nil {
+		return err
+	}
+	defer producer.Close()
+
+	n, err := dump.Import(ctx, r, producer, keepPartitions)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %v records into %v\n", n, topicN)
+	return nil
+}
+
+func parseInts(s string) ([]int, error) {
+	var out []int
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", f)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,236 @@
+package dump
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"producer/kafka"
+	"time"
+)
+
+// A binary dump starts with magic and a version byte. Every record is a
+// uvarint length followed by:
+//
+//	topic      uvarint length + bytes
+//	partition  varint
+//	offset     varint
+//	timestamp  varint, unix milliseconds, -1 for none
+//	key        varint length + bytes, -1 for null
+//	value      varint length + bytes, -1 for null
+//	headers    uvarint count, then per header a uvarint length + key and
+//	           a varint length + value
+//
+// Integers use the encoding/binary varint encodings.
+const (
+	magic         = "KDMP"
+	binaryVersion = 1
+)
+
+// maxRecord bounds the record length read from a dump so that a corrupt
+// file doesn't allocate without limit.
+const maxRecord = 64 << 20
+
+type binaryWriter struct {
+	w       *bufio.Writer
+	buf     []byte
+	started bool
+}
+
+// NewBinaryWriter returns a Writer of the binary format.
+func NewBinaryWriter(w io.Writer) Writer {
+	return &binaryWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *binaryWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if _, err := w.w.WriteString(magic); err != nil {
+		return err
+	}
+	return w.w.WriteByte(binaryVersion)
+}
+
+func (w *binaryWriter) Write(m kafka.Message) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	b := w.buf[:0]
+	b = appendString(b, m.Topic)
+	b = binary.AppendVarint(b, int64(m.Partition))
+	b = binary.AppendVarint(b, m.Offset)
+	ts := int64(-1)
+	if !m.Time.IsZero() {
+		ts = m.Time.UnixMilli()
+	}
+	b = binary.AppendVarint(b, ts)
+	b = appendBytes(b, m.Key)
+	b = appendBytes(b, m.Value)
+	b = binary.AppendUvarint(b, uint64(len(m.Headers)))
+	for _, h := range m.Headers {
+		b = appendString(b, h.Key)
+		b = appendBytes(b, h.Value)
+	}
+	w.buf = b
+
+	var size [binary.MaxVarintLen64]byte
+	if _, err := w.w.Write(size[:binary.PutUvarint(size[:], uint64(len(b)))]); err != nil {
+		return err
+	}
+	_, err := w.w.Write(b)
+	return err
+}
+
+func (w *binaryWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendBytes(b, v []byte) []byte {
+	if v == nil {
+		return binary.AppendVarint(b, -1)
+	}
+	b = binary.AppendVarint(b, int64(len(v)))
+	return append(b, v...)
+}
+
+// errCorrupt is returned for records that don't decode.
+var errCorrupt = errors.New("corrupt binary dump")
+
+type binaryReader struct {
+	r      *bufio.Reader
+	header bool
+	n      int
+}
+
+func newBinaryReader(r *bufio.Reader) *binaryReader {
+	return &binaryReader{r: r}
+}
+
+func (r *binaryReader) Read() (kafka.Message, error) {
+	if !r.header {
+		head := make([]byte, len(magic)+1)
+		if _, err := io.ReadFull(r.r, head); err != nil {
+			return kafka.Message{}, fmt.Errorf("reading dump header: %w", err)
+		}
+		if string(head[:len(magic)]) != magic {
+			return kafka.Message{}, errCorrupt
+		}
+		if v := head[len(magic)]; v != binaryVersion {
+			return kafka.Message{}, fmt.Errorf("unsupported binary dump version %v", v)
+		}
+		r.header = true
+	}
+
+	size, err := binary.ReadUvarint(r.r)
+	if err == io.EOF {
+		return kafka.Message{}, io.EOF
+	}
+	if err != nil || size > maxRecord {
+		return kafka.Message{}, fmt.Errorf("dump record %v: %w", r.n+1, errCorrupt)
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return kafka.Message{}, fmt.Errorf("dump record %v: %w", r.n+1, errCorrupt)
+	}
+	m, err := decodeRecord(buf)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("dump record %v: %w", r.n+1, err)
+	}
+	r.n++
+	return m, nil
+}
+
+// decoder reads the fields of one record, remembering the first error.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = errCorrupt
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = errCorrupt
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) take(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(len(d.b)) {
+		d.err = errCorrupt
+		return nil
+	}
+	v := d.b[:n:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.varint()
+	if d.err != nil || n == -1 {
+		return nil
+	}
+	if n < 0 {
+		d.err = errCorrupt
+		return nil
+	}
+	return d.take(uint64(n))
+}
+
+func (d *decoder) string() string {
+	return string(d.take(d.uvarint()))
+}
+
+func decodeRecord(b []byte) (kafka.Message, error) {
+	d := &decoder{b: b}
+	m := kafka.Message{
+		Topic:     d.string(),
+		Partition: int(d.varint()),
+		Offset:    d.varint(),
+	}
+	if ts := d.varint(); ts != -1 {
+		m.Time = time.UnixMilli(ts)
+	}
+	m.Key = d.bytes()
+	m.Value = d.bytes()
+	headers := d.uvarint()
+	for i := uint64(0); i < headers && d.err == nil; i++ {
+		m.Headers = append(m.Headers, kafka.Header{Key: d.string(), Value: d.bytes()})
+	}
+	if d.err == nil && len(d.b) > 0 {
+		d.err = errCorrupt
+	}
+	return m, d.err
+}
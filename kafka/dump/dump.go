@@ -0,0 +1,128 @@
+// Package dump saves topic records to files and produces them back, for
+// backups and test fixtures.
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"producer/kafka"
+)
+
+// Format is a dump file format.
+type Format string
+
+const (
+	// JSONLines writes one JSON object per record with base64 keys and
+	// values.
+	JSONLines Format = "jsonl"
+	// Binary writes length-prefixed records after a short file header.
+	Binary Format = "binary"
+)
+
+// ParseFormat returns the format named s.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case JSONLines, Binary:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown dump format %q, want %v or %v", s, JSONLines, Binary)
+}
+
+// Writer writes records to a dump. Close flushes it but leaves the
+// underlying io.Writer open.
+type Writer interface {
+	Write(m kafka.Message) error
+	Close() error
+}
+
+// Reader reads records from a dump. Read returns io.EOF after the last
+// record.
+type Reader interface {
+	Read() (kafka.Message, error)
+}
+
+// NewWriter returns a Writer for format f.
+func NewWriter(w io.Writer, f Format) (Writer, error) {
+	switch f {
+	case JSONLines:
+		return NewJSONWriter(w), nil
+	case Binary:
+		return NewBinaryWriter(w), nil
+	}
+	return nil, fmt.Errorf("unknown dump format %q", f)
+}
+
+// NewReader returns a Reader for r, telling the format from the first
+// bytes.
+func NewReader(r io.Reader) (Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(magic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(head, []byte(magic)) {
+		return newBinaryReader(br), nil
+	}
+	return newJSONReader(br), nil
+}
+
+// Export writes the records of rng in the topic set in opts to w and
+// returns how many it wrote. It does not close w.
+func Export(ctx context.Context, w Writer, rng kafka.ScanRange, opts ...kafka.Option) (int, error) {
+	n := 0
+	err := kafka.Scan(ctx, rng, func(m kafka.Message) error {
+		if err := w.Write(m); err != nil {
+			return err
+		}
+		n++
+		return nil
+	}, opts...)
+	return n, err
+}
+
+// importBatch is how many records Import sends at once.
+const importBatch = 500
+
+// Import produces every record of r with p and returns how many it sent.
+// Keys, headers and timestamps are kept. With keepPartitions records go to
+// the partition they were read from, otherwise p picks one from the key,
+// which keeps records of a key together when the target topic has a
+// different partition count.
+func Import(ctx context.Context, r Reader, p *kafka.Producer, keepPartitions bool) (int, error) {
+	n := 0
+	batch := make([]kafka.Message, 0, importBatch)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := p.Send(ctx, batch...); err != nil {
+			return err
+		}
+		n += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		in, err := r.Read()
+		if err == io.EOF {
+			return n, flush()
+		}
+		if err != nil {
+			return n, err
+		}
+		out := kafka.Message{Key: in.Key, Value: in.Value, Headers: in.Headers, Time: in.Time}
+		if keepPartitions {
+			out.SetPartition(in.Partition)
+		}
+		batch = append(batch, out)
+		if len(batch) == importBatch {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package dump
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"producer/kafka"
+	"time"
+)
+
+// record is one line of a JSON Lines dump. Keys and values are base64 and
+// null when absent.
+type record struct {
+	Topic     string    `json:"topic"`
+	Partition int       `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Timestamp time.Time `json:"timestamp"`
+	Key       []byte    `json:"key"`
+	Value     []byte    `json:"value"`
+	Headers   []header  `json:"headers,omitempty"`
+}
+
+type header struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type jsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewJSONWriter returns a Writer of JSON Lines.
+func NewJSONWriter(w io.Writer) Writer {
+	bw := bufio.NewWriter(w)
+	return &jsonWriter{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (w *jsonWriter) Write(m kafka.Message) error {
+	rec := record{
+		Topic:     m.Topic,
+		Partition: m.Partition,
+		Offset:    m.Offset,
+		Timestamp: m.Time,
+		Key:       m.Key,
+		Value:     m.Value,
+	}
+	for _, h := range m.Headers {
+		rec.Headers = append(rec.Headers, header{Key: h.Key, Value: h.Value})
+	}
+	return w.enc.Encode(rec)
+}
+
+func (w *jsonWriter) Close() error {
+	return w.w.Flush()
+}
+
+type jsonReader struct {
+	dec  *json.Decoder
+	line int
+}
+
+func newJSONReader(r io.Reader) *jsonReader {
+	return &jsonReader{dec: json.NewDecoder(r)}
+}
+
+func (r *jsonReader) Read() (kafka.Message, error) {
+	var rec record
+	if err := r.dec.Decode(&rec); err != nil {
+		if err == io.EOF {
+			return kafka.Message{}, err
+		}
+		return kafka.Message{}, fmt.Errorf("dump record %v: %w", r.line+1, err)
+	}
+	r.line++
+
+	m := kafka.Message{
+		Topic:     rec.Topic,
+		Partition: rec.Partition,
+		Offset:    rec.Offset,
+		Time:      rec.Timestamp,
+		Key:       rec.Key,
+		Value:     rec.Value,
+	}
+	for _, h := range rec.Headers {
+		m.Headers = append(m.Headers, kafka.Header{Key: h.Key, Value: h.Value})
+	}
+	return m, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// histogramBounds are the upper bounds of the latency buckets; anything
+// slower lands in a final overflow bucket.
+var histogramBounds = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+}
+
+type histogram struct {
+	mu      sync.Mutex
+	samples []time.Duration
+}
+
+func (h *histogram) add(d time.Duration) {
+	h.mu.Lock()
+	h.samples = append(h.samples, d)
+	h.mu.Unlock()
+}
+
+type latencyBucket struct {
+	// Le is the bucket's upper bound, zero for the overflow bucket.
+	Le    time.Duration `json:"le"`
+	Count int64         `json:"count"`
+}
+
+type latencySummary struct {
+	Count   int64           `json:"count"`
+	Min     time.Duration   `json:"min"`
+	P50     time.Duration   `json:"p50"`
+	P90     time.Duration   `json:"p90"`
+	P95     time.Duration   `json:"p95"`
+	P99     time.Duration   `json:"p99"`
+	P999    time.Duration   `json:"p999"`
+	Max     time.Duration   `json:"max"`
+	Buckets []latencyBucket `json:"buckets"`
+}
+
+func (h *histogram) summary() latencySummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := latencySummary{Count: int64(len(h.samples))}
+	s.Buckets = make([]latencyBucket, len(histogramBounds)+1)
+	for i, b := range histogramBounds {
+		s.Buckets[i].Le = b
+	}
+	if len(h.samples) == 0 {
+		return s
+	}
+
+	sort.Slice(h.samples, func(i, j int) bool { return h.samples[i] < h.samples[j] })
+	at := func(p float64) time.Duration {
+		idx := int(p / 100 * float64(len(h.samples)))
+		if idx >= len(h.samples) {
+			idx = len(h.samples) - 1
+		}
+		return h.samples[idx]
+	}
+	s.Min = h.samples[0]
+	s.P50 = at(50)
+	s.P90 = at(90)
+	s.P95 = at(95)
+	s.P99 = at(99)
+	s.P999 = at(99.9)
+	s.Max = h.samples[len(h.samples)-1]
+
+	for _, d := range h.samples {
+		i := sort.Search(len(histogramBounds), func(i int) bool { return d <= histogramBounds[i] })
+		s.Buckets[i].Count++
+	}
+	return s
+}
+
+func (s latencySummary) write(w io.Writer, title string) error {
+	us := func(d time.Duration) time.Duration { return d.Round(time.Microsecond) }
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%v\tcount\tmin\tp50\tp90\tp95\tp99\tp99.9\tmax\n", title)
+	fmt.Fprintf(tw, "\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+		s.Count, us(s.Min), us(s.P50), us(s.P90), us(s.P95), us(s.P99), us(s.P999), us(s.Max))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if s.Count == 0 {
+		return nil
+	}
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, b := range s.Buckets {
+		le := "> " + histogramBounds[len(histogramBounds)-1].String()
+		if b.Le != 0 {
+			le = "<= " + b.Le.String()
+		}
+		bar := strings.Repeat("#", int(b.Count*50/s.Count))
+		fmt.Fprintf(tw, "  %v\t%v\t%v\n", le, b.Count, bar)
+	}
+	return tw.Flush()
+}
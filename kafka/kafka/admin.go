@@ -0,0 +1,365 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// ErrUnknownTopic is wrapped by Admin errors about topics that do not exist.
+var ErrUnknownTopic = errors.New("kafka: unknown topic")
+
+// configSourceTopic is the DescribeConfigs source of values set on the
+// topic itself rather than inherited from the broker.
+const configSourceTopic = 1
+
+// TopicSpec is the desired shape of a topic.
+type TopicSpec struct {
+	Name       string
+	Partitions int
+	// ReplicationFactor is only used on creation; -1 leaves it to the broker.
+	ReplicationFactor int
+	Configs           map[string]string
+}
+
+// TopicInfo describes an existing topic.
+type TopicInfo struct {
+	Name       string          `json:"name"`
+	Internal   bool            `json:"internal"`
+	Partitions []PartitionInfo `json:"partitions"`
+	Configs    []ConfigEntry   `json:"configs"`
+}
+
+type PartitionInfo struct {
+	ID       int   `json:"id"`
+	Leader   int   `json:"leader"`
+	Replicas []int `json:"replicas"`
+	ISR      []int `json:"isr"`
+}
+
+type ConfigEntry struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Override is set for values configured on the topic rather than
+	// inherited from the broker defaults.
+	Override  bool `json:"override"`
+	ReadOnly  bool `json:"read_only"`
+	Sensitive bool `json:"sensitive"`
+}
+
+// Config returns the effective value of a topic config.
+func (t TopicInfo) Config(name string) (string, bool) {
+	for _, e := range t.Configs {
+		if e.Name == name {
+			return e.Value, true
+		}
+	}
+	return "", false
+}
+
+// Admin manages topics through the Kafka protocol.
+type Admin struct {
+	cfg       config
+	client    *kafkago.Client
+	transport *kafkago.Transport
+}
+
+func NewAdmin(opts ...Option) (*Admin, error) {
+	cfg, err := newClientConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newAdmin(cfg), nil
+}
+
+func newAdmin(cfg config) *Admin {
+	t := cfg.transport()
+	return &Admin{
+		cfg:       cfg,
+		transport: t,
+		client: &kafkago.Client{
+			Addr:      kafkago.TCP(cfg.brokers...),
+			Timeout:   cfg.readTimeout,
+			Transport: t,
+		},
+	}
+}
+
+// ListTopics returns the names of all non-internal topics, sorted.
+func (a *Admin) ListTopics(ctx context.Context) ([]string, error) {
+	meta, err := a.client.Metadata(ctx, &kafkago.MetadataRequest{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, t := range meta.Topics {
+		if !t.Internal {
+			names = append(names, t.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (a *Admin) CreateTopic(ctx context.Context, spec TopicSpec) error {
+	tc := kafkago.TopicConfig{
+		Topic:             spec.Name,
+		NumPartitions:     spec.Partitions,
+		ReplicationFactor: spec.ReplicationFactor,
+	}
+	if tc.NumPartitions == 0 {
+		tc.NumPartitions = -1
+	}
+	if tc.ReplicationFactor == 0 {
+		tc.ReplicationFactor = -1
+	}
+	for _, name := range sortedKeys(spec.Configs) {
+		tc.ConfigEntries = append(tc.ConfigEntries, kafkago.ConfigEntry{
+			ConfigName:  name,
+			ConfigValue: spec.Configs[name],
+		})
+	}
+
+	resp, err := a.client.CreateTopics(ctx, &kafkago.CreateTopicsRequest{
+		Topics: []kafkago.TopicConfig{tc},
+	})
+	if err != nil {
+		return err
+	}
+	if err := resp.Errors[spec.Name]; err != nil {
+		return fmt.Errorf("creating topic %v: %w", spec.Name, err)
+	}
+	return nil
+}
+
+// DescribeTopic returns a topic's partitions and its effective configs.
+func (a *Admin) DescribeTopic(ctx context.Context, name string) (TopicInfo, error) {
+	t, err := a.metadata(ctx, name)
+	if err != nil {
+		return TopicInfo{}, err
+	}
+
+	info := TopicInfo{Name: t.Name, Internal: t.Internal}
+	for _, p := range t.Partitions {
+		info.Partitions = append(info.Partitions, PartitionInfo{
+			ID:       p.ID,
+			Leader:   p.Leader.ID,
+			Replicas: brokerIDs(p.Replicas),
+			ISR:      brokerIDs(p.Isr),
+		})
+	}
+	sort.Slice(info.Partitions, func(i, j int) bool {
+		return info.Partitions[i].ID < info.Partitions[j].ID
+	})
+
+	resp, err := a.client.DescribeConfigs(ctx, &kafkago.DescribeConfigsRequest{
+		Resources: []kafkago.DescribeConfigRequestResource{{
+			ResourceType: kafkago.ResourceTypeTopic,
+			ResourceName: name,
+		}},
+	})
+	if err != nil {
+		return TopicInfo{}, err
+	}
+	for _, r := range resp.Resources {
+		if r.Error != nil {
+			return TopicInfo{}, fmt.Errorf("describing configs of %v: %w", name, r.Error)
+		}
+		for _, e := range r.ConfigEntries {
+			info.Configs = append(info.Configs, ConfigEntry{
+				Name:      e.ConfigName,
+				Value:     e.ConfigValue,
+				Override:  e.ConfigSource == configSourceTopic || e.ConfigSource == 0 && !e.IsDefault,
+				ReadOnly:  e.ReadOnly,
+				Sensitive: e.IsSensitive,
+			})
+		}
+	}
+	sort.Slice(info.Configs, func(i, j int) bool {
+		return info.Configs[i].Name < info.Configs[j].Name
+	})
+	return info, nil
+}
+
+// SetPartitions grows a topic to n partitions. Kafka cannot remove
+// partitions, so asking for fewer than the topic has is an error.
+func (a *Admin) SetPartitions(ctx context.Context, name string, n int) error {
+	t, err := a.metadata(ctx, name)
+	if err != nil {
+		return err
+	}
+	switch count := len(t.Partitions); {
+	case n == count:
+		return nil
+	case n < count:
+		return fmt.Errorf("topic %v already has %v partitions, partitions cannot be removed", name, count)
+	}
+
+	resp, err := a.client.CreatePartitions(ctx, &kafkago.CreatePartitionsRequest{
+		Topics: []kafkago.TopicPartitionsConfig{{
+			Name:  name,
+			Count: int32(n),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	if err := resp.Errors[name]; err != nil {
+		return fmt.Errorf("adding partitions to %v: %w", name, err)
+	}
+	return a.waitForPartitions(ctx, name, n)
+}
+
+// AlterConfigs sets the given configs on a topic, leaving others untouched.
+// An empty value removes the override so the broker default applies again.
+func (a *Admin) AlterConfigs(ctx context.Context, name string, configs map[string]string) error {
+	if len(configs) == 0 {
+		return nil
+	}
+	res := kafkago.IncrementalAlterConfigsRequestResource{
+		ResourceType: kafkago.ResourceTypeTopic,
+		ResourceName: name,
+	}
+	for _, k := range sortedKeys(configs) {
+		op := kafkago.ConfigOperationSet
+		if configs[k] == "" {
+			op = kafkago.ConfigOperationDelete
+		}
+		res.Configs = append(res.Configs, kafkago.IncrementalAlterConfigsRequestConfig{
+			Name:            k,
+			Value:           configs[k],
+			ConfigOperation: op,
+		})
+	}
+
+	resp, err := a.client.IncrementalAlterConfigs(ctx, &kafkago.IncrementalAlterConfigsRequest{
+		Resources: []kafkago.IncrementalAlterConfigsRequestResource{res},
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range resp.Resources {
+		if r.Error != nil {
+			return fmt.Errorf("altering configs of %v: %w", name, r.Error)
+		}
+	}
+	return nil
+}
+
+func (a *Admin) DeleteTopic(ctx context.Context, name string) error {
+	resp, err := a.client.DeleteTopics(ctx, &kafkago.DeleteTopicsRequest{
+		Topics: []string{name},
+	})
+	if err != nil {
+		return err
+	}
+	if err := resp.Errors[name]; err != nil {
+		if errors.Is(err, kafkago.UnknownTopicOrPartition) {
+			return fmt.Errorf("%w: %v", ErrUnknownTopic, name)
+		}
+		return fmt.Errorf("deleting topic %v: %w", name, err)
+	}
+	return nil
+}
+
+// EnsureTopic makes the topic match spec and is safe to call on every
+// start: it creates a missing topic, adds partitions when the topic has
+// fewer than spec asks for and sets configs that differ. A topic that has
+// grown beyond spec is left alone; partitions are never removed and the
+// replication factor of an existing topic is never changed.
+func (a *Admin) EnsureTopic(ctx context.Context, spec TopicSpec) error {
+	info, err := a.DescribeTopic(ctx, spec.Name)
+	switch {
+	case errors.Is(err, ErrUnknownTopic):
+		err := a.CreateTopic(ctx, spec)
+		switch {
+		case err == nil:
+			// creation is asynchronous on the broker, wait until metadata agrees
+			return a.waitForPartitions(ctx, spec.Name, spec.Partitions)
+		case !errors.Is(err, kafkago.TopicAlreadyExists):
+			return err
+		}
+		// another client created it first, possibly with other settings,
+		// so reconcile with whatever it made
+		if err := a.waitForPartitions(ctx, spec.Name, 0); err != nil {
+			return err
+		}
+		if info, err = a.DescribeTopic(ctx, spec.Name); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	}
+
+	if spec.Partitions > len(info.Partitions) {
+		if err := a.SetPartitions(ctx, spec.Name, spec.Partitions); err != nil {
+			return err
+		}
+	}
+
+	changed := map[string]string{}
+	for k, v := range spec.Configs {
+		if cur, ok := info.Config(k); !ok || cur != v {
+			changed[k] = v
+		}
+	}
+	return a.AlterConfigs(ctx, spec.Name, changed)
+}
+
+// Close releases the admin client's connections.
+func (a *Admin) Close() error {
+	a.transport.CloseIdleConnections()
+	return nil
+}
+
+func (a *Admin) metadata(ctx context.Context, name string) (kafkago.Topic, error) {
+	meta, err := a.client.Metadata(ctx, &kafkago.MetadataRequest{Topics: []string{name}})
+	if err != nil {
+		return kafkago.Topic{}, err
+	}
+	if len(meta.Topics) == 0 || errors.Is(meta.Topics[0].Error, kafkago.UnknownTopicOrPartition) {
+		return kafkago.Topic{}, fmt.Errorf("%w: %v", ErrUnknownTopic, name)
+	}
+	if err := meta.Topics[0].Error; err != nil {
+		return kafkago.Topic{}, err
+	}
+	return meta.Topics[0], nil
+}
+
+// waitForPartitions polls metadata until the topic has at least n
+// partitions, or any number of them when n is zero. Another client may have
+// created the topic with more.
+func (a *Admin) waitForPartitions(ctx context.Context, name string, n int) error {
+	for {
+		t, err := a.metadata(ctx, name)
+		if err == nil && len(t.Partitions) > 0 && len(t.Partitions) >= n {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func brokerIDs(brokers []kafkago.Broker) []int {
+	ids := make([]int, len(brokers))
+	for i, b := range brokers {
+		ids[i] = b.ID
+	}
+	return ids
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
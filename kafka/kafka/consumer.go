@@ -0,0 +1,157 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+var (
+	ErrClosed = errors.New("kafka: consumer closed")
+
+	errSeekWithGroup = errors.New("kafka: group consumers can't seek, reset the group's offsets with Admin.ResetOffsets while it is stopped")
+)
+
+// Handler processes one consumed message. Returning an error stops Run.
+// ctx is cancelled when Run stops or the group takes the partition away;
+// returning ctx's error then is not treated as a failure.
+type Handler func(ctx context.Context, m Message) error
+
+// Consumer reads a topic, as part of a consumer group when one is set.
+type Consumer struct {
+	cfg  config
+	flow *flow
+
+	mu sync.Mutex
+	// assigned holds the partition readers while runAssigned runs.
+	assigned []*kafkago.Reader
+	// seek is where the next Run starts, set by SeekTime while not running.
+	seek   time.Time
+	stop   func()
+	closed bool
+}
+
+func NewConsumer(opts ...Option) (*Consumer, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.checkRebalance(); err != nil {
+		return nil, err
+	}
+	return &Consumer{cfg: cfg, flow: newFlow(cfg)}, nil
+}
+
+// seekGroup applies the start time to the group's committed offsets.
+func (c *Consumer) seekGroup(ctx context.Context) error {
+	if c.cfg.startTime.IsZero() || c.cfg.group == "" {
+		return nil
+	}
+	admin := newAdmin(c.cfg)
+	defer admin.Close()
+	_, err := admin.ResetOffsets(ctx, c.cfg.group, c.cfg.topic, ToTime(c.cfg.startTime))
+	return err
+}
+
+// SeekTime moves a consumer outside a group to the first message at or
+// after t on each partition it reads, or makes the next Run start there
+// when it is not running. Messages already fetched may still be handled
+// before the seek takes effect. Group consumers can't seek since their
+// position is the group's committed offsets; WithStartTime resets those
+// when Run starts.
+func (c *Consumer) SeekTime(ctx context.Context, t time.Time) error {
+	if c.cfg.group != "" {
+		return errSeekWithGroup
+	}
+	c.mu.Lock()
+	readers := c.assigned
+	if readers == nil {
+		c.seek = t
+	}
+	c.mu.Unlock()
+	for _, r := range readers {
+		if err := r.SetOffsetAt(ctx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// track records how Close stops the running Run; nil clears it.
+func (c *Consumer) track(stop func()) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed && stop != nil {
+		return ErrClosed
+	}
+	c.stop = stop
+	return nil
+}
+
+// Run fetches messages and passes them to h until ctx is cancelled, which is
+// not reported as an error, or until fetching, committing or h fails.
+//
+// Group consumers commit offsets according to the delivery semantics: with
+// AtLeastOnce a message is committed only after h returned nil for it, so a
+// crash or handler error leads to redelivery; with AtMostOnce it is
+// committed before h sees it, so it is never redelivered. Pending commits
+// are flushed before Run returns.
+//
+// Every partition is read with its own reader, so a paused or slow
+// partition doesn't hold back the others. Consumers outside a group without
+// an assignment read all partitions the topic has when Run starts, from the
+// oldest record unless WithStartTime or SeekTime say otherwise.
+func (c *Consumer) Run(ctx context.Context, h Handler) error {
+	switch {
+	case len(c.cfg.assignment) > 0:
+		return c.runAssigned(ctx, c.cfg.assignment, h)
+	case c.cfg.group != "":
+		return c.runGroup(ctx, h)
+	}
+
+	assignment, err := c.allPartitions(ctx)
+	if err != nil {
+		return err
+	}
+	return c.runAssigned(ctx, assignment, h)
+}
+
+// allPartitions assigns every partition of the topic from its first offset.
+func (c *Consumer) allPartitions(ctx context.Context) ([]Assignment, error) {
+	admin := newAdmin(c.cfg)
+	defer admin.Close()
+	t, err := admin.metadata(ctx, c.cfg.topic)
+	if err != nil {
+		return nil, err
+	}
+	assignment := make([]Assignment, len(t.Partitions))
+	for i, p := range t.Partitions {
+		assignment[i] = Assignment{Partition: p.ID, Offset: FirstOffset}
+	}
+	return assignment, nil
+}
+
+func (c *Consumer) Topic() string {
+	return c.cfg.topic
+}
+
+func (c *Consumer) Group() string {
+	return c.cfg.group
+}
+
+func (c *Consumer) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	stop := c.stop
+	c.mu.Unlock()
+
+	// stopping a group run waits for OnRevoke, which must not hold mu
+	if stop != nil {
+		stop()
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package kafka
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Headers carrying envelope metadata. The trace headers follow W3C Trace
+// Context, so other instrumented clients pick them up.
+const (
+	HeaderEventType     = "event-type"
+	HeaderSchemaVersion = "schema-version"
+	HeaderCorrelationID = "correlation-id"
+	HeaderTraceParent   = "traceparent"
+	HeaderTraceState    = "tracestate"
+)
+
+// Envelope is a Message together with the metadata that travels with its
+// payload in headers.
+type Envelope struct {
+	Message
+
+	EventType     string
+	SchemaVersion string
+	CorrelationID string
+	Trace         TraceContext
+}
+
+// TraceContext is a W3C trace context.
+type TraceContext struct {
+	// Parent is the traceparent value, version-traceid-spanid-flags.
+	Parent string
+	State  string
+}
+
+// NewTrace starts a new sampled trace.
+func NewTrace() TraceContext {
+	return TraceContext{Parent: fmt.Sprintf("00-%v-%v-01", randomHex(16), randomHex(8))}
+}
+
+// TraceID returns the trace ID, or "" when Parent is not a valid traceparent.
+func (t TraceContext) TraceID() string {
+	parts := strings.Split(t.Parent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ""
+	}
+	return parts[1]
+}
+
+// Child returns the context of a new span in the same trace, used when
+// forwarding a message on behalf of the one that was received. Invalid
+// contexts start a new trace.
+func (t TraceContext) Child() TraceContext {
+	id := t.TraceID()
+	if id == "" {
+		return NewTrace()
+	}
+	flags := t.Parent[len(t.Parent)-2:]
+	return TraceContext{
+		Parent: fmt.Sprintf("00-%v-%v-%v", id, randomHex(8), flags),
+		State:  t.State,
+	}
+}
+
+// NewCorrelationID returns a random ID for a new conversation.
+func NewCorrelationID() string {
+	return randomHex(16)
+}
+
+// ToMessage returns the envelope's message with the metadata written to
+// headers, replacing any headers of the same name.
+func (e Envelope) ToMessage() Message {
+	m := e.Message
+	m.Headers = append([]Header(nil), e.Headers...)
+	for _, h := range []struct{ key, value string }{
+		{HeaderEventType, e.EventType},
+		{HeaderSchemaVersion, e.SchemaVersion},
+		{HeaderCorrelationID, e.CorrelationID},
+		{HeaderTraceParent, e.Trace.Parent},
+		{HeaderTraceState, e.Trace.State},
+	} {
+		if h.value != "" {
+			m.SetHeader(h.key, []byte(h.value))
+		}
+	}
+	return m
+}
+
+// OpenEnvelope reads the metadata headers of m into an envelope. The
+// metadata headers are removed from the embedded message's headers.
+func OpenEnvelope(m Message) Envelope {
+	e := Envelope{Message: m}
+	e.Headers = nil
+	for _, h := range m.Headers {
+		v := string(h.Value)
+		switch h.Key {
+		case HeaderEventType:
+			e.EventType = v
+		case HeaderSchemaVersion:
+			e.SchemaVersion = v
+		case HeaderCorrelationID:
+			e.CorrelationID = v
+		case HeaderTraceParent:
+			e.Trace.Parent = v
+		case HeaderTraceState:
+			e.Trace.State = v
+		default:
+			e.Headers = append(e.Headers, h)
+		}
+	}
+	return e
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
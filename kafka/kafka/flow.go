@@ -0,0 +1,197 @@
+package kafka
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// WithMaxInFlight bounds how many fetched messages a consumer hands on
+// before they are handled, across all of its partitions, so a slow handler
+// doesn't make it read far ahead. Each partition's reader also prefetches at
+// most n messages, and WithMaxBytes still bounds the size of a single fetch.
+func WithMaxInFlight(n int) Option {
+	return func(c *config) { c.maxInFlight = n }
+}
+
+// WithRateLimit caps how many messages and how many bytes of keys and
+// values a consumer hands to its handler per second. Zero leaves a limit
+// off. Short bursts of up to one second's worth are let through.
+func WithRateLimit(messagesPerSecond, bytesPerSecond float64) Option {
+	return func(c *config) {
+		c.messageRate = messagesPerSecond
+		c.byteRate = bytesPerSecond
+	}
+}
+
+// flow decides when a fetched message may be handled: not while its
+// partition is paused, not before it is due with WithRetryDelay and not
+// faster than the rate limits.
+type flow struct {
+	mu      sync.Mutex
+	paused  map[int]bool
+	resumed chan struct{}
+
+	retryDelay bool
+	// slots holds a token for every admitted message that is not handled
+	// yet; nil without WithMaxInFlight
+	slots chan struct{}
+
+	messages *limiter
+	bytes    *limiter
+}
+
+func newFlow(cfg config) *flow {
+	var slots chan struct{}
+	if cfg.maxInFlight > 0 {
+		slots = make(chan struct{}, cfg.maxInFlight)
+	}
+	return &flow{
+		slots:      slots,
+		paused:     map[int]bool{},
+		resumed:    make(chan struct{}),
+		retryDelay: cfg.retryDelay,
+		messages:   newLimiter(cfg.messageRate),
+		bytes:      newLimiter(cfg.byteRate),
+	}
+}
+
+// admit waits until m may be handled or ctx is done. Every admitted message
+// must be released once it is handled or dropped.
+func (f *flow) admit(ctx context.Context, m kafkago.Message) error {
+	for {
+		f.mu.Lock()
+		paused, resumed := f.paused[m.Partition], f.resumed
+		f.mu.Unlock()
+		if !paused {
+			break
+		}
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if f.retryDelay {
+		if err := waitUntilDue(ctx, m); err != nil {
+			return err
+		}
+	}
+	if err := f.messages.wait(ctx, 1); err != nil {
+		return err
+	}
+	if err := f.bytes.wait(ctx, float64(len(m.Key)+len(m.Value))); err != nil {
+		return err
+	}
+	if f.slots == nil {
+		return nil
+	}
+	select {
+	case f.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees the slot of an admitted message.
+func (f *flow) release() {
+	if f.slots != nil {
+		<-f.slots
+	}
+}
+
+func (f *flow) pause(partitions []int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range partitions {
+		f.paused[p] = true
+	}
+}
+
+func (f *flow) resume(partitions []int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range partitions {
+		delete(f.paused, p)
+	}
+	// wake every waiter, those of still paused partitions wait again
+	close(f.resumed)
+	f.resumed = make(chan struct{})
+}
+
+func (f *flow) pausedPartitions() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]int, 0, len(f.paused))
+	for p := range f.paused {
+		out = append(out, p)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Pause stops handing messages of partitions to the handler until they are
+// resumed, for example while the system they are written to is unhealthy.
+// Offsets of paused messages are not committed. Each partition is read on
+// its own, so the others keep flowing and their commits go on.
+func (c *Consumer) Pause(partitions ...int) {
+	c.flow.pause(partitions)
+}
+
+// Resume undoes Pause.
+func (c *Consumer) Resume(partitions ...int) {
+	c.flow.resume(partitions)
+}
+
+// Paused returns the paused partitions in order.
+func (c *Consumer) Paused() []int {
+	return c.flow.pausedPartitions()
+}
+
+// limiter is a token bucket refilled at rate per second that holds up to
+// one second's worth. A request larger than what is left is let through
+// once the bucket has refilled enough, so no size is refused.
+type limiter struct {
+	rate float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newLimiter returns nil, which never waits, for rates of zero or less.
+func newLimiter(rate float64) *limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &limiter{rate: rate, tokens: rate, last: time.Now()}
+}
+
+func (l *limiter) wait(ctx context.Context, n float64) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= n
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,230 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+type GroupSummary struct {
+	ID           string `json:"id"`
+	ProtocolType string `json:"protocol_type"`
+	Coordinator  int    `json:"coordinator"`
+}
+
+type GroupMember struct {
+	ID       string `json:"id"`
+	ClientID string `json:"client_id"`
+	Host     string `json:"host"`
+	// Assignments maps topics to the partitions assigned to the member.
+	Assignments map[string][]int `json:"assignments"`
+}
+
+type GroupInfo struct {
+	ID      string        `json:"id"`
+	State   string        `json:"state"`
+	Members []GroupMember `json:"members"`
+}
+
+// PartitionLag compares a group's committed offset on a partition with the
+// partition's high watermark.
+type PartitionLag struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	// Committed is -1 when the group has not committed on the partition;
+	// its lag then counts every message still retained.
+	Committed     int64  `json:"committed"`
+	HighWatermark int64  `json:"high_watermark"`
+	Lag           int64  `json:"lag"`
+	Member        string `json:"member,omitempty"`
+}
+
+type GroupLag struct {
+	GroupInfo
+	Partitions []PartitionLag `json:"partitions"`
+	Total      int64          `json:"total"`
+}
+
+// ListGroups returns every consumer group known to the cluster, sorted by ID.
+// Each broker only reports the groups it coordinates; kafka-go sends the
+// request to every broker in the cluster's metadata and merges the answers,
+// failing if any broker can't be asked.
+func (a *Admin) ListGroups(ctx context.Context) ([]GroupSummary, error) {
+	resp, err := a.client.ListGroups(ctx, &kafkago.ListGroupsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("listing groups: %w", err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("listing groups: %w", resp.Error)
+	}
+	// a group moving to another coordinator may be reported by both
+	byID := map[string]GroupSummary{}
+	for _, g := range resp.Groups {
+		byID[g.GroupID] = GroupSummary{ID: g.GroupID, ProtocolType: g.ProtocolType, Coordinator: g.Coordinator}
+	}
+	out := make([]GroupSummary, 0, len(byID))
+	for _, g := range byID {
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// DescribeGroup returns a group's state and its members' assignments.
+func (a *Admin) DescribeGroup(ctx context.Context, group string) (GroupInfo, error) {
+	resp, err := a.client.DescribeGroups(ctx, &kafkago.DescribeGroupsRequest{GroupIDs: []string{group}})
+	if err != nil {
+		return GroupInfo{}, err
+	}
+	if len(resp.Groups) == 0 {
+		return GroupInfo{}, fmt.Errorf("describing group %v: no response", group)
+	}
+	g := resp.Groups[0]
+	if g.Error != nil {
+		return GroupInfo{}, fmt.Errorf("describing group %v: %w", group, g.Error)
+	}
+
+	info := GroupInfo{ID: g.GroupID, State: g.GroupState}
+	for _, m := range g.Members {
+		member := GroupMember{
+			ID:          m.MemberID,
+			ClientID:    m.ClientID,
+			Host:        m.ClientHost,
+			Assignments: map[string][]int{},
+		}
+		for _, t := range m.MemberAssignments.Topics {
+			parts := append([]int(nil), t.Partitions...)
+			sort.Ints(parts)
+			member.Assignments[t.Topic] = parts
+		}
+		info.Members = append(info.Members, member)
+	}
+	sort.Slice(info.Members, func(i, j int) bool { return info.Members[i].ID < info.Members[j].ID })
+	return info, nil
+}
+
+// GroupLag reports, for every partition of every topic the group has
+// committed on or is assigned, how far the group is behind.
+func (a *Admin) GroupLag(ctx context.Context, group string) (GroupLag, error) {
+	info, err := a.DescribeGroup(ctx, group)
+	if err != nil {
+		return GroupLag{}, err
+	}
+
+	committed, err := a.client.OffsetFetch(ctx, &kafkago.OffsetFetchRequest{GroupID: group})
+	if err != nil {
+		return GroupLag{}, err
+	}
+	if committed.Error != nil {
+		return GroupLag{}, fmt.Errorf("fetching offsets of %v: %w", group, committed.Error)
+	}
+
+	owner := map[topicPartition]string{}
+	topics := map[string]bool{}
+	for _, m := range info.Members {
+		for t, parts := range m.Assignments {
+			topics[t] = true
+			for _, p := range parts {
+				owner[topicPartition{t, p}] = m.ID
+			}
+		}
+	}
+	offsets := map[topicPartition]int64{}
+	for t, parts := range committed.Topics {
+		for _, p := range parts {
+			if p.Error != nil {
+				return GroupLag{}, fmt.Errorf("fetching offsets of %v on %v/%v: %w", group, t, p.Partition, p.Error)
+			}
+			if p.CommittedOffset >= 0 {
+				topics[t] = true
+				offsets[topicPartition{t, p.Partition}] = p.CommittedOffset
+			}
+		}
+	}
+
+	lag := GroupLag{GroupInfo: info}
+	if len(topics) == 0 {
+		return lag, nil
+	}
+
+	marks, err := a.watermarks(ctx, topics)
+	if err != nil {
+		return GroupLag{}, err
+	}
+	for tp, w := range marks {
+		pl := PartitionLag{
+			Topic:         tp.topic,
+			Partition:     tp.partition,
+			Committed:     -1,
+			HighWatermark: w.last,
+			Member:        owner[tp],
+		}
+		if off, ok := offsets[tp]; ok {
+			pl.Committed = off
+			pl.Lag = max(w.last-off, 0)
+		} else {
+			pl.Lag = w.last - w.first
+		}
+		lag.Total += pl.Lag
+		lag.Partitions = append(lag.Partitions, pl)
+	}
+	sort.Slice(lag.Partitions, func(i, j int) bool {
+		a, b := lag.Partitions[i], lag.Partitions[j]
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Partition < b.Partition
+	})
+	return lag, nil
+}
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+type watermark struct {
+	first, last int64
+}
+
+// watermarks returns the log start and high watermark of every partition of
+// topics.
+func (a *Admin) watermarks(ctx context.Context, topics map[string]bool) (map[topicPartition]watermark, error) {
+	names := make([]string, 0, len(topics))
+	for t := range topics {
+		names = append(names, t)
+	}
+	meta, err := a.client.Metadata(ctx, &kafkago.MetadataRequest{Topics: names})
+	if err != nil {
+		return nil, err
+	}
+
+	req := &kafkago.ListOffsetsRequest{Topics: map[string][]kafkago.OffsetRequest{}}
+	for _, t := range meta.Topics {
+		if t.Error != nil {
+			// deleted since the group committed on it
+			continue
+		}
+		for _, p := range t.Partitions {
+			req.Topics[t.Name] = append(req.Topics[t.Name],
+				kafkago.FirstOffsetOf(p.ID), kafkago.LastOffsetOf(p.ID))
+		}
+	}
+	resp, err := a.client.ListOffsets(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[topicPartition]watermark{}
+	for t, parts := range resp.Topics {
+		for _, p := range parts {
+			if p.Error != nil {
+				return nil, fmt.Errorf("listing offsets of %v/%v: %w", t, p.Partition, p.Error)
+			}
+			out[topicPartition{t, p.Partition}] = watermark{first: p.FirstOffset, last: p.LastOffset}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package kafka
+
+import (
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Message is a record produced to or consumed from a topic. Topic,
+// Partition, Offset and Time are filled in on consumed messages. When
+// producing, a zero Time means now and the partition is chosen by the
+// producer unless SetPartition was called.
+type Message struct {
+	Key     []byte
+	Value   []byte
+	Headers []Header
+
+	Topic     string
+	Partition int
+	Offset    int64
+	Time      time.Time
+
+	pinned bool
+}
+
+// SetPartition makes the producer write m to partition p instead of
+// choosing one from the key.
+func (m *Message) SetPartition(p int) {
+	m.Partition = p
+	m.pinned = true
+}
+
+// Header is a record header. Keys may repeat.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Header returns the value of the last header named key.
+func (m Message) Header(key string) ([]byte, bool) {
+	for i := len(m.Headers) - 1; i >= 0; i-- {
+		if m.Headers[i].Key == key {
+			return m.Headers[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// SetHeader replaces every header named key with a single one.
+func (m *Message) SetHeader(key string, value []byte) {
+	out := m.Headers[:0:0]
+	for _, h := range m.Headers {
+		if h.Key != key {
+			out = append(out, h)
+		}
+	}
+	m.Headers = append(out, Header{Key: key, Value: value})
+}
+
+func (m Message) toKafka() kafkago.Message {
+	km := kafkago.Message{
+		Key:   m.Key,
+		Value: m.Value,
+		Time:  m.Time,
+		// kafka-go doesn't take a partition on writes, so the balancers
+		// find it here; only read by the explicit partitioner unless pinned
+		WriterData: partitionData{partition: m.Partition, pinned: m.pinned},
+	}
+	for _, h := range m.Headers {
+		km.Headers = append(km.Headers, kafkago.Header{Key: h.Key, Value: h.Value})
+	}
+	return km
+}
+
+func fromKafka(m kafkago.Message) Message {
+	out := Message{
+		Key:       m.Key,
+		Value:     m.Value,
+		Topic:     m.Topic,
+		Partition: m.Partition,
+		Offset:    m.Offset,
+		Time:      m.Time,
+	}
+	for _, h := range m.Headers {
+		out.Headers = append(out.Headers, Header{Key: h.Key, Value: h.Value})
+	}
+	return out
+}
@@ -0,0 +1,301 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RenameRule renames topics matching From to To, which may refer to From's
+// capture groups as $1 and so on.
+type RenameRule struct {
+	From *regexp.Regexp
+	To   string
+}
+
+// ParseRenameRule parses "regexp=replacement".
+func ParseRenameRule(s string) (RenameRule, error) {
+	from, to, ok := strings.Cut(s, "=")
+	if !ok {
+		return RenameRule{}, fmt.Errorf("kafka: rename rule %q is not from=to", s)
+	}
+	re, err := regexp.Compile("^(?:" + from + ")$")
+	if err != nil {
+		return RenameRule{}, fmt.Errorf("kafka: rename rule %q: %w", s, err)
+	}
+	return RenameRule{From: re, To: to}, nil
+}
+
+// MirrorConfig describes what a Mirror copies and where to.
+type MirrorConfig struct {
+	// Source and Target configure the two clusters; topics are set by the
+	// mirror. WithKeyWorkers in Source gives up the order of records with
+	// different keys in a partition.
+	Source []Option
+	Target []Option
+	// Group names the source consumer groups whose committed offsets
+	// checkpoint the mirror's progress. Each topic has a group of its own,
+	// <Group>.<topic>, so that topics found later don't rebalance the ones
+	// already running.
+	Group string
+
+	// Include and Exclude select source topics by name. No Include means
+	// every topic not excluded.
+	Include []*regexp.Regexp
+	Exclude []*regexp.Regexp
+	// Rename maps source to target topic names; the first matching rule
+	// wins and unmatched topics keep their name.
+	Rename []RenameRule
+
+	// PreservePartitions writes every record to the partition number it
+	// had in the source, so the target needs at least as many partitions.
+	PreservePartitions bool
+	// CreateTopics creates missing target topics with the source's
+	// partition count.
+	CreateTopics bool
+	// Refresh is how often the source is checked for new topics; zero
+	// checks only at start.
+	Refresh time.Duration
+	// OnSkip, if set, is called once for every selected topic that is not
+	// mirrored, e.g. because on the same cluster it is the target of
+	// another topic.
+	OnSkip func(topic, reason string)
+}
+
+// Mirror copies topics from one cluster or topic to another, preserving
+// keys, headers and timestamps.
+type Mirror struct {
+	cfg    MirrorConfig
+	source *Admin
+	target *Admin
+
+	mu      sync.Mutex
+	running map[string]bool
+	skipped map[string]bool
+}
+
+func NewMirror(cfg MirrorConfig) (*Mirror, error) {
+	if cfg.Group == "" {
+		return nil, errors.New("kafka: mirror needs a group to checkpoint progress")
+	}
+	source, err := NewAdmin(cfg.Source...)
+	if err != nil {
+		return nil, fmt.Errorf("mirror source: %w", err)
+	}
+	target, err := NewAdmin(cfg.Target...)
+	if err != nil {
+		source.Close()
+		return nil, fmt.Errorf("mirror target: %w", err)
+	}
+	return &Mirror{cfg: cfg, source: source, target: target, running: map[string]bool{}, skipped: map[string]bool{}}, nil
+}
+
+// TargetTopic returns the name source is mirrored to.
+func (m *Mirror) TargetTopic(source string) string {
+	for _, r := range m.cfg.Rename {
+		if r.From.MatchString(source) {
+			return r.From.ReplaceAllString(source, r.To)
+		}
+	}
+	return source
+}
+
+func (m *Mirror) selected(topic string) bool {
+	if strings.HasPrefix(topic, "__") {
+		return false
+	}
+	for _, re := range m.cfg.Exclude {
+		if re.MatchString(topic) {
+			return false
+		}
+	}
+	if len(m.cfg.Include) == 0 {
+		return true
+	}
+	for _, re := range m.cfg.Include {
+		if re.MatchString(topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Topics lists the source topics currently selected for mirroring. When
+// source and target are the same cluster, topics that are the target of
+// another selected topic or that would be mirrored onto themselves are
+// skipped, so a mirror never picks up its own output.
+func (m *Mirror) Topics(ctx context.Context) ([]string, error) {
+	all, err := m.source.ListTopics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var selected []string
+	for _, t := range all {
+		if m.selected(t) {
+			selected = append(selected, t)
+		}
+	}
+	if !sameCluster(m.source.cfg.brokers, m.target.cfg.brokers) {
+		return selected, nil
+	}
+
+	targets := map[string]string{}
+	for _, t := range selected {
+		if target := m.TargetTopic(t); target != t {
+			targets[target] = t
+		}
+	}
+	var out []string
+	for _, t := range selected {
+		switch {
+		case targets[t] != "":
+			m.skip(t, fmt.Sprintf("it is the mirror target of %v", targets[t]))
+		case m.TargetTopic(t) == t:
+			m.skip(t, "source and target are the same topic")
+		default:
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// skip reports topic to OnSkip the first time it is skipped.
+func (m *Mirror) skip(topic, reason string) {
+	m.mu.Lock()
+	first := !m.skipped[topic]
+	m.skipped[topic] = true
+	m.mu.Unlock()
+	if first && m.cfg.OnSkip != nil {
+		m.cfg.OnSkip(topic, reason)
+	}
+}
+
+// Run mirrors the selected topics until ctx is cancelled or one of them
+// fails.
+func (m *Mirror) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		rerr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			rerr = err
+			cancel()
+		})
+	}
+
+	for {
+		topics, err := m.Topics(ctx)
+		if err != nil {
+			fail(err)
+		}
+		for _, t := range topics {
+			if m.start(t) {
+				wg.Add(1)
+				go func(t string) {
+					defer wg.Done()
+					if err := m.mirror(ctx, t); err != nil {
+						fail(fmt.Errorf("mirroring %v: %w", t, err))
+					}
+				}(t)
+			}
+		}
+
+		var refresh <-chan time.Time
+		if m.cfg.Refresh > 0 {
+			refresh = time.After(m.cfg.Refresh)
+		}
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return rerr
+		case <-refresh:
+		}
+	}
+}
+
+// start reports whether topic still needs a goroutine and claims it.
+func (m *Mirror) start(topic string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running[topic] {
+		return false
+	}
+	m.running[topic] = true
+	return true
+}
+
+func (m *Mirror) mirror(ctx context.Context, topic string) error {
+	target := m.TargetTopic(topic)
+	if sameCluster(m.source.cfg.brokers, m.target.cfg.brokers) && target == topic {
+		return errors.New("source and target are the same topic")
+	}
+
+	if m.cfg.CreateTopics || m.cfg.PreservePartitions {
+		info, err := m.source.DescribeTopic(ctx, topic)
+		if err != nil {
+			return err
+		}
+		if m.cfg.CreateTopics {
+			if err := m.target.EnsureTopic(ctx, TopicSpec{Name: target, Partitions: len(info.Partitions)}); err != nil {
+				return err
+			}
+		}
+		if m.cfg.PreservePartitions {
+			t, err := m.target.DescribeTopic(ctx, target)
+			if err != nil {
+				return err
+			}
+			if len(t.Partitions) < len(info.Partitions) {
+				return fmt.Errorf("target %v has %v partitions, source has %v", target, len(t.Partitions), len(info.Partitions))
+			}
+		}
+	}
+
+	p, err := NewProducer(append(append([]Option(nil), m.cfg.Target...), WithTopic(target))...)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	c, err := NewConsumer(append(append([]Option(nil), m.cfg.Source...), WithTopic(topic), WithGroup(mirrorGroup(m.cfg.Group, topic)))...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	return c.Run(ctx, func(ctx context.Context, in Message) error {
+		out := Message{Key: in.Key, Value: in.Value, Headers: in.Headers, Time: in.Time}
+		if m.cfg.PreservePartitions {
+			out.SetPartition(in.Partition)
+		}
+		return p.Send(ctx, out)
+	})
+}
+
+// Close releases the admin connections; Run must have returned.
+func (m *Mirror) Close() error {
+	return errors.Join(m.source.Close(), m.target.Close())
+}
+
+func mirrorGroup(group, topic string) string {
+	return group + "." + topic
+}
+
+func sameCluster(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
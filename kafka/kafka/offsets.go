@@ -0,0 +1,235 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// ErrGroupActive is returned when offsets of a group with live members are
+// reset; the members would overwrite them with their next commit.
+var ErrGroupActive = errors.New("kafka: consumer group has active members")
+
+type resetKind int
+
+const (
+	resetEarliest resetKind = iota
+	resetLatest
+	resetOffset
+	resetOffsets
+	resetTime
+	resetShift
+)
+
+// ResetTarget says where a group's offsets should move to.
+type ResetTarget struct {
+	kind    resetKind
+	offsets map[int]int64
+	at      time.Time
+	offset  int64
+	shift   int64
+}
+
+func ToEarliest() ResetTarget { return ResetTarget{kind: resetEarliest} }
+func ToLatest() ResetTarget   { return ResetTarget{kind: resetLatest} }
+
+// ToOffset moves every partition to offset n.
+func ToOffset(n int64) ResetTarget { return ResetTarget{kind: resetOffset, offset: n} }
+
+// ToOffsets moves the listed partitions to the given offsets and leaves the
+// others alone.
+func ToOffsets(offsets map[int]int64) ResetTarget {
+	return ResetTarget{kind: resetOffsets, offsets: offsets}
+}
+
+// ToTime moves every partition to its first message at or after t.
+func ToTime(t time.Time) ResetTarget { return ResetTarget{kind: resetTime, at: t} }
+
+// ShiftBy moves every partition n messages forward, or back when negative.
+func ShiftBy(n int64) ResetTarget { return ResetTarget{kind: resetShift, shift: n} }
+
+// ParseResetTarget parses earliest, latest, shift:N, time:RFC3339,
+// offset:N for every partition or offset:P=N,P=N for some of them.
+func ParseResetTarget(s string) (ResetTarget, error) {
+	kind, arg, _ := strings.Cut(s, ":")
+	switch strings.ToLower(kind) {
+	case "earliest":
+		return ToEarliest(), nil
+	case "latest":
+		return ToLatest(), nil
+	case "shift":
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return ResetTarget{}, fmt.Errorf("invalid shift %q", arg)
+		}
+		return ShiftBy(n), nil
+	case "time":
+		t, err := time.Parse(time.RFC3339, arg)
+		if err != nil {
+			return ResetTarget{}, fmt.Errorf("invalid time %q, want RFC3339", arg)
+		}
+		return ToTime(t), nil
+	case "offset":
+		if n, err := strconv.ParseInt(arg, 10, 64); err == nil {
+			return ToOffset(n), nil
+		}
+		offsets := map[int]int64{}
+		for _, pair := range strings.Split(arg, ",") {
+			p, o, ok := strings.Cut(pair, "=")
+			part, perr := strconv.Atoi(p)
+			off, oerr := strconv.ParseInt(o, 10, 64)
+			if !ok || perr != nil || oerr != nil {
+				return ResetTarget{}, fmt.Errorf("invalid partition offset %q, want partition=offset", pair)
+			}
+			offsets[part] = off
+		}
+		return ToOffsets(offsets), nil
+	}
+	return ResetTarget{}, fmt.Errorf("unknown reset target %q", s)
+}
+
+// OffsetChange is one partition's committed offset before and after a reset.
+// Before is -1 when the group had not committed on the partition.
+type OffsetChange struct {
+	Partition int   `json:"partition"`
+	Before    int64 `json:"before"`
+	After     int64 `json:"after"`
+}
+
+// PlanOffsetReset computes where a reset would move the group's offsets on
+// topic without changing anything. Targets are clamped to the offsets still
+// retained.
+func (a *Admin) PlanOffsetReset(ctx context.Context, group, topic string, target ResetTarget) ([]OffsetChange, error) {
+	marks, err := a.watermarks(ctx, map[string]bool{topic: true})
+	if err != nil {
+		return nil, err
+	}
+	if len(marks) == 0 {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownTopic, topic)
+	}
+
+	committed, err := a.client.OffsetFetch(ctx, &kafkago.OffsetFetchRequest{GroupID: group})
+	if err != nil {
+		return nil, err
+	}
+	if committed.Error != nil {
+		return nil, fmt.Errorf("fetching offsets of %v: %w", group, committed.Error)
+	}
+	before := map[int]int64{}
+	for _, p := range committed.Topics[topic] {
+		if p.Error == nil && p.CommittedOffset >= 0 {
+			before[p.Partition] = p.CommittedOffset
+		}
+	}
+
+	var atTime map[int]int64
+	if target.kind == resetTime {
+		if atTime, err = a.offsetsAt(ctx, topic, marks, target.at); err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []OffsetChange
+	for tp, w := range marks {
+		cur, ok := before[tp.partition]
+		if !ok {
+			cur = -1
+		}
+		var after int64
+		switch target.kind {
+		case resetEarliest:
+			after = w.first
+		case resetLatest:
+			after = w.last
+		case resetOffset:
+			after = target.offset
+		case resetOffsets:
+			off, ok := target.offsets[tp.partition]
+			if !ok {
+				continue
+			}
+			after = off
+		case resetTime:
+			after = atTime[tp.partition]
+		case resetShift:
+			if cur < 0 {
+				return nil, fmt.Errorf("group %v has no offset on %v/%v to shift", group, topic, tp.partition)
+			}
+			after = cur + target.shift
+		}
+		after = min(max(after, w.first), w.last)
+		changes = append(changes, OffsetChange{Partition: tp.partition, Before: cur, After: after})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Partition < changes[j].Partition })
+	return changes, nil
+}
+
+// ResetOffsets moves the group's offsets on topic and returns what changed.
+// The group must have no active members.
+func (a *Admin) ResetOffsets(ctx context.Context, group, topic string, target ResetTarget) ([]OffsetChange, error) {
+	info, err := a.DescribeGroup(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.Members) > 0 {
+		return nil, fmt.Errorf("%w: %v is %v with %v members", ErrGroupActive, group, info.State, len(info.Members))
+	}
+
+	changes, err := a.PlanOffsetReset(ctx, group, topic, target)
+	if err != nil {
+		return nil, err
+	}
+	commits := make([]kafkago.OffsetCommit, len(changes))
+	for i, c := range changes {
+		commits[i] = kafkago.OffsetCommit{Partition: c.Partition, Offset: c.After}
+	}
+
+	// outside a generation the coordinator accepts commits from anyone
+	resp, err := a.client.OffsetCommit(ctx, &kafkago.OffsetCommitRequest{
+		GroupID:      group,
+		GenerationID: -1,
+		Topics:       map[string][]kafkago.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("committing %v/%v for %v: %w", topic, p.Partition, group, p.Error)
+		}
+	}
+	return changes, nil
+}
+
+// offsetsAt returns, per partition, the first offset whose timestamp is at
+// or after t, or the high watermark when there is none.
+func (a *Admin) offsetsAt(ctx context.Context, topic string, marks map[topicPartition]watermark, t time.Time) (map[int]int64, error) {
+	req := &kafkago.ListOffsetsRequest{Topics: map[string][]kafkago.OffsetRequest{}}
+	for tp := range marks {
+		req.Topics[topic] = append(req.Topics[topic], kafkago.TimeOffsetOf(tp.partition, t))
+	}
+	resp, err := a.client.ListOffsets(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[int]int64{}
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("listing offsets of %v/%v: %w", topic, p.Partition, p.Error)
+		}
+		out[p.Partition] = marks[topicPartition{topic, p.Partition}].last
+		for off := range p.Offsets {
+			if off >= 0 && off < out[p.Partition] {
+				out[p.Partition] = off
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,156 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"errors"
+	"time"
+
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+var (
+	ErrNoBrokers = errors.New("kafka: no brokers configured")
+	ErrNoTopic   = errors.New("kafka: no topic configured")
+)
+
+const (
+	defaultDialTimeout  = 10 * time.Second
+	defaultReadTimeout  = 10 * time.Second
+	defaultWriteTimeout = 10 * time.Second
+	defaultMaxBytes     = 10e6
+	defaultBatchSize    = 100
+	defaultBatchBytes   = 1048576
+	defaultLinger       = time.Second
+	defaultMaxAttempts  = 10
+)
+
+type config struct {
+	brokers      []string
+	topic        string
+	group        string
+	clientID     string
+	dialTimeout  time.Duration
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	maxBytes     int
+	startTime    time.Time
+
+	batchSize   int
+	batchBytes  int64
+	linger      time.Duration
+	acks        Acks
+	maxAttempts int
+	compression Compression
+	partitioner Partitioner
+	async       bool
+	onDelivery  func(DeliveryReport)
+
+	semantics      Semantics
+	commitBatch    int
+	commitInterval time.Duration
+	keyWorkers     int
+	hooks          *RebalanceHooks
+	assignment     []Assignment
+	maxInFlight    int
+	retryDelay     bool
+	messageRate    float64
+	byteRate       float64
+
+	tls  *tls.Config
+	sasl sasl.Mechanism
+	// err collects failures of options that load files or build
+	// mechanisms, reported when the client is created
+	err error
+}
+
+// Option configures a Producer, Consumer or Admin. Options that only make
+// sense for some of them are ignored by the others.
+type Option func(*config)
+
+func WithBrokers(brokers ...string) Option {
+	return func(c *config) { c.brokers = brokers }
+}
+
+func WithTopic(topic string) Option {
+	return func(c *config) { c.topic = topic }
+}
+
+// WithGroup sets the consumer group. Consumers without a group read every
+// partition of the topic themselves.
+func WithGroup(group string) Option {
+	return func(c *config) { c.group = group }
+}
+
+func WithClientID(id string) Option {
+	return func(c *config) { c.clientID = id }
+}
+
+func WithDialTimeout(d time.Duration) Option {
+	return func(c *config) { c.dialTimeout = d }
+}
+
+// WithReadTimeout bounds how long a producer waits for responses and a
+// consumer waits to read a fetched batch.
+func WithReadTimeout(d time.Duration) Option {
+	return func(c *config) { c.readTimeout = d }
+}
+
+func WithWriteTimeout(d time.Duration) Option {
+	return func(c *config) { c.writeTimeout = d }
+}
+
+// WithMaxBytes caps how much a consumer fetches from a partition at once.
+func WithMaxBytes(n int) Option {
+	return func(c *config) { c.maxBytes = n }
+}
+
+// WithStartTime makes a consumer start at the first message at or after t.
+// Group consumers reset the group's committed offsets, which fails while
+// other members of the group are running.
+// Consumers outside a group can move again while running with SeekTime.
+func WithStartTime(t time.Time) Option {
+	return func(c *config) { c.startTime = t }
+}
+
+func newConfig(opts []Option) (config, error) {
+	c, err := newClientConfig(opts)
+	if err != nil {
+		return c, err
+	}
+	if c.topic == "" {
+		return c, ErrNoTopic
+	}
+	return c, nil
+}
+
+// newClientConfig is newConfig for clients that are not bound to a topic.
+func newClientConfig(opts []Option) (config, error) {
+	c := config{
+		dialTimeout:  defaultDialTimeout,
+		readTimeout:  defaultReadTimeout,
+		writeTimeout: defaultWriteTimeout,
+		maxBytes:     defaultMaxBytes,
+		batchSize:    defaultBatchSize,
+		batchBytes:   defaultBatchBytes,
+		linger:       defaultLinger,
+		acks:         AcksAll,
+		maxAttempts:  defaultMaxAttempts,
+		compression:  CompressionNone,
+		partitioner:  PartitionerHash,
+		semantics:    AtLeastOnce,
+		commitBatch:  1,
+	}
+	for _, o := range append(envSecurity(), opts...) {
+		o(&c)
+	}
+	if c.err != nil {
+		return c, c.err
+	}
+	if len(c.brokers) == 0 {
+		return c, ErrNoBrokers
+	}
+	if c.commitBatch < 1 {
+		c.commitBatch = 1
+	}
+	return c, nil
+}
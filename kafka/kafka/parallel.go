@@ -0,0 +1,248 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// workerQueue is how many messages may wait for each key worker.
+const workerQueue = 64
+
+// WithKeyWorkers makes a consumer hand messages to n workers instead of
+// handling them one by one. Messages with the same key always go to the same
+// worker, so they are handled in order; messages without a key go to one
+// worker per partition, so they keep their order among themselves but not
+// relative to keyed messages. Offsets are only committed up to the first
+// message of each partition that is not handled yet.
+func WithKeyWorkers(n int) Option {
+	return func(c *config) { c.keyWorkers = n }
+}
+
+// result is a fetched message, or a handled one and the handler's error.
+type result struct {
+	m   kafkago.Message
+	err error
+}
+
+// workerLoop is handleLoop for consumers with key workers: it hands
+// messages to the workers and commits, when commit is set, up to the first
+// message of each partition that is not handled yet.
+func (c *Consumer) workerLoop(ctx context.Context, msgs <-chan kafkago.Message, errs <-chan error, end <-chan struct{}, h Handler, commit func(map[int]int64) error) error {
+	workers := c.cfg.keyWorkers
+	maxInFlight := workers * workerQueue
+	if c.cfg.maxInFlight > 0 {
+		maxInFlight = c.cfg.maxInFlight
+	}
+	commits := commit != nil && c.cfg.semantics == AtLeastOnce
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// queues and results are as large as what may be in flight, so neither
+	// side ever blocks on the other
+	results := make(chan result, maxInFlight)
+	queues := make([]chan kafkago.Message, workers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan kafkago.Message, maxInFlight)
+		wg.Add(1)
+		go func(q <-chan kafkago.Message) {
+			defer wg.Done()
+			for m := range q {
+				err := ctx.Err()
+				if err == nil {
+					err = h(ctx, fromKafka(m))
+				}
+				c.flow.release()
+				results <- result{m, err}
+			}
+		}(queues[i])
+	}
+	stop := func() {
+		cancel()
+		for _, q := range queues {
+			close(q)
+		}
+		wg.Wait()
+	}
+
+	b := &commitBatch{
+		commit:  commit,
+		size:    c.cfg.commitBatch,
+		pending: map[int]int64{},
+	}
+	tracker := newOffsetTracker()
+	var tick <-chan time.Time
+	if commits && c.cfg.commitInterval > 0 {
+		t := time.NewTicker(c.cfg.commitInterval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	inFlight := 0
+	for {
+		in := msgs
+		if inFlight >= maxInFlight {
+			in = nil
+		}
+
+		select {
+		case m := <-in:
+			if commit != nil && c.cfg.semantics == AtMostOnce {
+				if err := commit(map[int]int64{m.Partition: m.Offset + 1}); err != nil {
+					c.flow.release()
+					stop()
+					return err
+				}
+			}
+			tracker.add(m)
+			inFlight++
+			queues[workerFor(m, workers)] <- m
+
+		case err := <-errs:
+			stop()
+			return errors.Join(err, drain(results, tracker, b, commits))
+
+		case res := <-results:
+			inFlight--
+			if res.err != nil {
+				// decide before stop cancels ctx for the other workers
+				if interrupted(ctx, res.err) {
+					res.err = nil
+				}
+				stop()
+				return errors.Join(res.err, drain(results, tracker, b, commits))
+			}
+			if done, ok := tracker.done(res.m); ok && commits {
+				b.add(done)
+				if b.count >= b.size {
+					if err := b.flush(); err != nil {
+						stop()
+						return err
+					}
+				}
+			}
+
+		case <-tick:
+			if err := b.flush(); err != nil {
+				stop()
+				return err
+			}
+
+		case <-end:
+			stop()
+			return drain(results, tracker, b, commits)
+
+		case <-ctx.Done():
+			stop()
+			return drain(results, tracker, b, commits)
+		}
+	}
+}
+
+// drain records what the stopped workers finished successfully and flushes
+// the commits that allows.
+func drain(results chan result, tracker *offsetTracker, b *commitBatch, commits bool) error {
+	for {
+		select {
+		case res := <-results:
+			if res.err != nil {
+				continue
+			}
+			if done, ok := tracker.done(res.m); ok && commits {
+				b.add(done)
+			}
+		default:
+			if !commits {
+				return nil
+			}
+			return b.flush()
+		}
+	}
+}
+
+// commitBatch tracks the next offset to read per partition until the batch
+// is committed.
+type commitBatch struct {
+	commit  func(map[int]int64) error
+	size    int
+	pending map[int]int64
+	count   int
+}
+
+func (b *commitBatch) add(m kafkago.Message) {
+	b.pending[m.Partition] = m.Offset + 1
+	b.count++
+}
+
+func (b *commitBatch) flush() error {
+	if b.count == 0 {
+		return nil
+	}
+	if err := b.commit(b.pending); err != nil {
+		return err
+	}
+	clear(b.pending)
+	b.count = 0
+	return nil
+}
+
+// workerFor picks the worker of m by its key, or by its partition when it
+// has none.
+func workerFor(m kafkago.Message, workers int) int {
+	if m.Key == nil {
+		return m.Partition % workers
+	}
+	h := fnv.New32a()
+	h.Write(m.Key)
+	return int(h.Sum32() % uint32(workers))
+}
+
+// offsetTracker follows the messages of each partition in fetch order and
+// finds the last one up to which everything has been handled.
+type offsetTracker struct {
+	partitions map[int]*partitionOffsets
+}
+
+type partitionOffsets struct {
+	inFlight []kafkago.Message
+	handled  map[int64]bool
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: map[int]*partitionOffsets{}}
+}
+
+func (t *offsetTracker) add(m kafkago.Message) {
+	p, ok := t.partitions[m.Partition]
+	if !ok {
+		p = &partitionOffsets{handled: map[int64]bool{}}
+		t.partitions[m.Partition] = p
+	}
+	p.inFlight = append(p.inFlight, m)
+}
+
+// done marks m handled and returns the newest message whose offset may now
+// be committed, if the contiguous handled prefix grew.
+func (t *offsetTracker) done(m kafkago.Message) (kafkago.Message, bool) {
+	p := t.partitions[m.Partition]
+	if p == nil {
+		return kafkago.Message{}, false
+	}
+	p.handled[m.Offset] = true
+
+	var last kafkago.Message
+	advanced := false
+	for len(p.inFlight) > 0 && p.handled[p.inFlight[0].Offset] {
+		last = p.inFlight[0]
+		delete(p.handled, last.Offset)
+		p.inFlight = p.inFlight[1:]
+		advanced = true
+	}
+	return last, advanced
+}
@@ -0,0 +1,76 @@
+package kafka
+
+import (
+	"testing"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	type step struct {
+		partition int
+		offset    int64
+		// commit is the offset that may be committed after the step, or -1
+		// when the handled prefix didn't grow
+		commit int64
+	}
+	tests := []struct {
+		name    string
+		fetched map[int][]int64
+		steps   []step
+	}{
+		{
+			name:    "in order",
+			fetched: map[int][]int64{0: {0, 1, 2}},
+			steps:   []step{{0, 0, 0}, {0, 1, 1}, {0, 2, 2}},
+		},
+		{
+			name:    "out of order",
+			fetched: map[int][]int64{0: {0, 1, 2, 3}},
+			steps:   []step{{0, 2, -1}, {0, 3, -1}, {0, 1, -1}, {0, 0, 3}},
+		},
+		{
+			name:    "first of several done late",
+			fetched: map[int][]int64{0: {0, 1, 2}},
+			steps:   []step{{0, 1, -1}, {0, 0, 1}, {0, 2, 2}},
+		},
+		{
+			name:    "gaps between offsets",
+			fetched: map[int][]int64{0: {3, 7, 8, 20}},
+			steps:   []step{{0, 7, -1}, {0, 3, 7}, {0, 20, -1}, {0, 8, 20}},
+		},
+		{
+			name:    "partitions are independent",
+			fetched: map[int][]int64{0: {0, 1}, 1: {5, 6}},
+			steps:   []step{{1, 5, 5}, {0, 1, -1}, {1, 6, 6}, {0, 0, 1}},
+		},
+		{
+			name:    "unknown partition",
+			fetched: map[int][]int64{0: {0}},
+			steps:   []step{{1, 0, -1}, {0, 0, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newOffsetTracker()
+			for p, offsets := range tt.fetched {
+				for _, o := range offsets {
+					tracker.add(kafkago.Message{Partition: p, Offset: o})
+				}
+			}
+			for i, s := range tt.steps {
+				m, ok := tracker.done(kafkago.Message{Partition: s.partition, Offset: s.offset})
+				got := int64(-1)
+				if ok {
+					got = m.Offset
+					if m.Partition != s.partition {
+						t.Fatalf("step %v: committable message of partition %v, want %v", i, m.Partition, s.partition)
+					}
+				}
+				if got != s.commit {
+					t.Fatalf("step %v: done %v/%v made %v committable, want %v", i, s.partition, s.offset, got, s.commit)
+				}
+			}
+		})
+	}
+}
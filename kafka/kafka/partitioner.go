@@ -0,0 +1,140 @@
+package kafka
+
+import (
+	"fmt"
+	"sync"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Partitioner picks the partition of messages that were not given one with
+// SetPartition.
+type Partitioner string
+
+const (
+	// PartitionerHash hashes keys with FNV-1a, the kafka-go default.
+	PartitionerHash Partitioner = "hash"
+	// PartitionerMurmur2 places keys like the Java client's default
+	// partitioner. Messages without a key go to a random partition.
+	PartitionerMurmur2 Partitioner = "murmur2"
+	// PartitionerCRC32 places keys like librdkafka's consistent_random.
+	PartitionerCRC32      Partitioner = "crc32"
+	PartitionerRoundRobin Partitioner = "round-robin"
+	// PartitionerLeastBytes picks the partition that received the fewest
+	// bytes so far.
+	PartitionerLeastBytes Partitioner = "least-bytes"
+	// PartitionerSticky places keys with murmur2 and keeps messages without
+	// a key on one partition for a whole batch, like the Java client since
+	// 2.4.
+	PartitionerSticky Partitioner = "sticky"
+	// PartitionerExplicit writes every message to its Partition field.
+	PartitionerExplicit Partitioner = "explicit"
+)
+
+// Partitioners lists every strategy, in the order tools should show them.
+var Partitioners = []Partitioner{
+	PartitionerHash,
+	PartitionerMurmur2,
+	PartitionerCRC32,
+	PartitionerRoundRobin,
+	PartitionerLeastBytes,
+	PartitionerSticky,
+	PartitionerExplicit,
+}
+
+func ParsePartitioner(s string) (Partitioner, error) {
+	p := Partitioner(s)
+	if s == "" {
+		p = PartitionerHash
+	}
+	if _, err := p.balancer(1); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// WithPartitioner sets how a producer spreads messages over partitions.
+func WithPartitioner(p Partitioner) Option {
+	return func(c *config) { c.partitioner = p }
+}
+
+// ByKey reports whether the strategy places a key on the same partition
+// every time.
+func (p Partitioner) ByKey() bool {
+	switch p {
+	case PartitionerHash, PartitionerMurmur2, PartitionerCRC32, PartitionerSticky:
+		return true
+	}
+	return false
+}
+
+// PartitionFor returns the partition key is written to on a topic with n
+// partitions.
+func (p Partitioner) PartitionFor(key []byte, n int) (int, error) {
+	if !p.ByKey() {
+		return 0, fmt.Errorf("kafka: partitioner %q does not place messages by key", string(p))
+	}
+	if key == nil {
+		key = []byte{}
+	}
+	b, err := p.balancer(1)
+	if err != nil {
+		return 0, err
+	}
+	partitions := make([]int, n)
+	for i := range partitions {
+		partitions[i] = i
+	}
+	return b.Balance(kafkago.Message{Key: key}, partitions...), nil
+}
+
+// balancer builds a fresh balancer; sticky ones switch partition every
+// batchSize messages.
+func (p Partitioner) balancer(batchSize int) (kafkago.Balancer, error) {
+	switch p {
+	case PartitionerHash, "":
+		return &kafkago.Hash{}, nil
+	case PartitionerMurmur2:
+		return kafkago.Murmur2Balancer{}, nil
+	case PartitionerCRC32:
+		return kafkago.CRC32Balancer{}, nil
+	case PartitionerRoundRobin:
+		return &kafkago.RoundRobin{}, nil
+	case PartitionerLeastBytes:
+		return &kafkago.LeastBytes{}, nil
+	case PartitionerSticky:
+		return &stickyBalancer{keyed: kafkago.Murmur2Balancer{}, batch: max(batchSize, 1)}, nil
+	case PartitionerExplicit:
+		return kafkago.BalancerFunc(func(m kafkago.Message, _ ...int) int {
+			d, _ := m.WriterData.(partitionData)
+			return d.partition
+		}), nil
+	}
+	return nil, fmt.Errorf("kafka: unknown partitioner %q", string(p))
+}
+
+// stickyBalancer sends messages without a key to one partition until batch
+// of them were sent, then moves on to the next.
+type stickyBalancer struct {
+	keyed kafkago.Balancer
+	batch int
+
+	mu    sync.Mutex
+	sent  int
+	index int
+}
+
+func (b *stickyBalancer) Balance(m kafkago.Message, partitions ...int) int {
+	if m.Key != nil {
+		return b.keyed.Balance(m, partitions...)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.sent >= b.batch {
+		b.sent = 0
+		b.index++
+	}
+	b.sent++
+	return partitions[b.index%len(partitions)]
+}
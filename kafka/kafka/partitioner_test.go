@@ -0,0 +1,35 @@
+package kafka_test
+
+import (
+	"producer/kafka"
+	"testing"
+)
+
+// TestMurmur2MatchesJava checks placement against the murmur2 hashes the
+// Java client's own tests use; its partitioner takes the hash with the sign
+// bit cleared modulo the partition count.
+func TestMurmur2MatchesJava(t *testing.T) {
+	tests := []struct {
+		key  string
+		hash int32
+	}{
+		{"21", -973932308},
+		{"foobar", -790332482},
+		{"a-little-bit-long-string", -985981536},
+		{"a-little-bit-longer-string", -1486304829},
+		{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", -58897971},
+		{"abc", 479470107},
+	}
+	for _, tt := range tests {
+		for _, n := range []int{1, 3, 12, 1000} {
+			want := int(tt.hash&0x7fffffff) % n
+			got, err := kafka.PartitionerMurmur2.PartitionFor([]byte(tt.key), n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("PartitionFor(%q, %v) = %v, want %v", tt.key, n, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package kafka
+
+import (
+	"context"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Producer writes messages to a single topic over a long-lived writer.
+type Producer struct {
+	cfg config
+	w   *kafkago.Writer
+}
+
+func NewProducer(opts ...Option) (*Producer, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := cfg.compression.codec()
+	if err != nil {
+		return nil, err
+	}
+	balancer, err := cfg.partitioner.balancer(cfg.batchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &kafkago.Writer{
+		Addr:         kafkago.TCP(cfg.brokers...),
+		Topic:        cfg.topic,
+		Balancer:     pinnedBalancer{balancer},
+		MaxAttempts:  cfg.maxAttempts,
+		BatchSize:    cfg.batchSize,
+		BatchBytes:   cfg.batchBytes,
+		BatchTimeout: cfg.linger,
+		ReadTimeout:  cfg.readTimeout,
+		WriteTimeout: cfg.writeTimeout,
+		RequiredAcks: kafkago.RequiredAcks(cfg.acks),
+		Async:        cfg.async,
+		Compression:  codec,
+		Transport:    cfg.transport(),
+	}
+	if fn := cfg.onDelivery; fn != nil {
+		w.Completion = func(msgs []kafkago.Message, err error) {
+			for _, m := range msgs {
+				fn(DeliveryReport{Message: fromKafka(m), Err: err})
+			}
+		}
+	}
+	return &Producer{cfg: cfg, w: w}, nil
+}
+
+// partitionData carries a message's Partition field to the balancer.
+type partitionData struct {
+	partition int
+	// pinned is set for messages whose partition was set with SetPartition
+	pinned bool
+}
+
+// pinnedBalancer sends pinned messages to their partition and leaves the
+// others to next.
+type pinnedBalancer struct {
+	next kafkago.Balancer
+}
+
+func (b pinnedBalancer) Balance(m kafkago.Message, partitions ...int) int {
+	if d, ok := m.WriterData.(partitionData); ok && d.pinned {
+		return d.partition
+	}
+	return b.next.Balance(m, partitions...)
+}
+
+// Send writes msgs. Synchronous producers block until the messages are
+// acknowledged according to the required acks; async producers return once
+// they are queued.
+func (p *Producer) Send(ctx context.Context, msgs ...Message) error {
+	km := make([]kafkago.Message, len(msgs))
+	for i, m := range msgs {
+		km[i] = m.toKafka()
+	}
+	return p.w.WriteMessages(ctx, km...)
+}
+
+func (p *Producer) Topic() string {
+	return p.cfg.topic
+}
+
+// Close flushes pending messages and releases the writer's connections.
+func (p *Producer) Close() error {
+	return p.w.Close()
+}
@@ -0,0 +1,391 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// RebalanceHooks tell a consumer's user which partitions it owns, so that
+// stateful handlers can load state for assigned partitions and flush it for
+// revoked ones. Errors returned by the hooks stop Run.
+type RebalanceHooks struct {
+	// OnGeneration is called when a group consumer joins a new generation
+	// of its group, before OnAssign.
+	OnGeneration func(generation int32, memberID string)
+	// OnAssign is called before the first message of partitions is handled.
+	OnAssign func(ctx context.Context, partitions []int) error
+	// OnRevoke is called after the last message of partitions was handled
+	// and its offset committed. The group does not hand the partitions to
+	// another member before OnRevoke returns.
+	OnRevoke func(ctx context.Context, partitions []int) error
+}
+
+// WithRebalanceHooks calls h as a group consumer's partitions change, or
+// once at the start and end of Run for consumers with WithAssignment.
+func WithRebalanceHooks(h RebalanceHooks) Option {
+	return func(c *config) { c.hooks = &h }
+}
+
+// Assignment is a partition to read and the offset to start at.
+type Assignment struct {
+	Partition int
+	Offset    int64
+}
+
+// Offsets an Assignment can start at besides absolute ones.
+const (
+	// FirstOffset is the oldest record still in the partition.
+	FirstOffset = kafkago.FirstOffset
+	// LastOffset is the next record produced to the partition.
+	LastOffset = kafkago.LastOffset
+)
+
+// WithAssignment makes a consumer read exactly these partitions without
+// joining a group, which suits tools that inspect or replay partitions.
+// Nothing is committed. WithStartTime overrides the offsets.
+func WithAssignment(a ...Assignment) Option {
+	return func(c *config) { c.assignment = a }
+}
+
+var (
+	errHooksWithoutGroup   = errors.New("kafka: rebalance hooks need a group or an assignment")
+	errAssignmentWithGroup = errors.New("kafka: an assignment can't be used with a group")
+	errWorkersWithHooks    = errors.New("kafka: key workers can't be used with rebalance hooks or an assignment")
+)
+
+// checkRebalance rejects option combinations the consumer doesn't support.
+func (c config) checkRebalance() error {
+	switch {
+	case len(c.assignment) > 0 && c.group != "":
+		return errAssignmentWithGroup
+	case c.hooks != nil && c.group == "" && len(c.assignment) == 0:
+		return errHooksWithoutGroup
+	case c.keyWorkers > 1 && (c.hooks != nil || len(c.assignment) > 0):
+		return errWorkersWithHooks
+	}
+	return nil
+}
+
+// partitionReader returns a reader of one partition outside any group.
+func (c config) partitionReader(partition int) *kafkago.Reader {
+	return kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:          c.brokers,
+		Topic:            c.topic,
+		Partition:        partition,
+		MaxBytes:         c.maxBytes,
+		ReadBatchTimeout: c.readTimeout,
+		QueueCapacity:    c.maxInFlight,
+		Dialer:           c.dialer(),
+	})
+}
+
+func (c *Consumer) assign(ctx context.Context, partitions []int) error {
+	if c.cfg.hooks == nil || c.cfg.hooks.OnAssign == nil {
+		return nil
+	}
+	return c.cfg.hooks.OnAssign(ctx, partitions)
+}
+
+// revoke runs OnRevoke even when ctx is done, since it usually flushes
+// state on the way out.
+func (c *Consumer) revoke(ctx context.Context, partitions []int) error {
+	if c.cfg.hooks == nil || c.cfg.hooks.OnRevoke == nil {
+		return nil
+	}
+	return c.cfg.hooks.OnRevoke(context.WithoutCancel(ctx), partitions)
+}
+
+// runAssigned is Run for consumers outside a group, reading assignment.
+func (c *Consumer) runAssigned(ctx context.Context, assignment []Assignment, h Handler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var readers []*kafkago.Reader
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+	for _, a := range assignment {
+		r := c.cfg.partitionReader(a.Partition)
+		readers = append(readers, r)
+		var err error
+		if c.cfg.startTime.IsZero() {
+			err = r.SetOffset(a.Offset)
+		} else {
+			err = r.SetOffsetAt(ctx, c.cfg.startTime)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := c.track(cancel); err != nil {
+		return err
+	}
+	defer c.track(nil)
+	c.mu.Lock()
+	c.assigned = readers
+	seek := c.seek
+	c.seek = time.Time{}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.assigned = nil
+		c.mu.Unlock()
+	}()
+	if !seek.IsZero() {
+		for _, r := range readers {
+			if err := r.SetOffsetAt(ctx, seek); err != nil {
+				return err
+			}
+		}
+	}
+
+	partitions := make([]int, len(assignment))
+	for i, a := range assignment {
+		partitions[i] = a.Partition
+	}
+	slices.Sort(partitions)
+	if err := c.assign(ctx, partitions); err != nil {
+		return err
+	}
+
+	msgs := make(chan kafkago.Message)
+	errs := make(chan error, len(readers))
+	var wg sync.WaitGroup
+	for _, r := range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := feedPartition(ctx, r, c.flow, msgs); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	loop := c.handleLoop
+	if c.cfg.keyWorkers > 1 {
+		loop = c.workerLoop
+	}
+	err := loop(ctx, msgs, errs, nil, h, nil)
+	cancel()
+	wg.Wait()
+	return errors.Join(err, c.revoke(ctx, partitions))
+}
+
+// runGroup is Run for group consumers. It drives the group protocol itself
+// instead of leaving it to a reader, which hides generations and reads all
+// partitions through one fetch loop.
+func (c *Consumer) runGroup(ctx context.Context, h Handler) error {
+	if err := c.seekGroup(ctx); err != nil {
+		return err
+	}
+	cg, err := kafkago.NewConsumerGroup(kafkago.ConsumerGroupConfig{
+		ID:      c.cfg.group,
+		Brokers: c.cfg.brokers,
+		Dialer:  c.cfg.dialer(),
+		Topics:  []string{c.cfg.topic},
+	})
+	if err != nil {
+		return err
+	}
+	// leaving the group on return lets a later Run join again
+	defer cg.Close()
+	if err := c.track(func() { cg.Close() }); err != nil {
+		return err
+	}
+	defer c.track(nil)
+
+	for {
+		gen, err := cg.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, kafkago.ErrGroupClosed) {
+				return nil
+			}
+			// failures to join are retried by the group with a backoff
+			continue
+		}
+		if err := c.runGeneration(ctx, gen, h); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// runGeneration handles the partitions of one generation until the group
+// rebalances, ctx is done or handling fails. A rebalance cancels the ctx of
+// running handlers; the message they were handling is not committed. The
+// generation is held open until offsets are committed and OnRevoke
+// returned.
+func (c *Consumer) runGeneration(ctx context.Context, gen *kafkago.Generation, h Handler) error {
+	assigned := gen.Assignments[c.cfg.topic]
+	partitions := make([]int, len(assigned))
+	for i, a := range assigned {
+		partitions[i] = a.ID
+	}
+	slices.Sort(partitions)
+
+	if c.cfg.hooks != nil && c.cfg.hooks.OnGeneration != nil {
+		c.cfg.hooks.OnGeneration(gen.ID, gen.MemberID)
+	}
+	if err := c.assign(ctx, partitions); err != nil {
+		return err
+	}
+
+	// handlers are cancelled when the group rebalances, so a slow one can't
+	// hold the generation open until the member is evicted
+	hctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ended := make(chan struct{})
+	finished := make(chan struct{})
+	gen.Start(func(genCtx context.Context) {
+		<-genCtx.Done()
+		close(ended)
+		cancel()
+		<-finished
+	})
+
+	msgs := make(chan kafkago.Message)
+	errs := make(chan error, len(assigned))
+	for _, a := range assigned {
+		gen.Start(func(genCtx context.Context) {
+			r := c.cfg.partitionReader(a.ID)
+			defer r.Close()
+			err := r.SetOffset(a.Offset)
+			if err == nil {
+				err = feedPartition(genCtx, r, c.flow, msgs)
+			}
+			if err != nil {
+				errs <- err
+			}
+		})
+	}
+
+	commit := func(offsets map[int]int64) error {
+		return gen.CommitOffsets(map[string]map[int]int64{c.cfg.topic: offsets})
+	}
+	loop := c.handleLoop
+	if c.cfg.keyWorkers > 1 {
+		loop = c.workerLoop
+	}
+	err := loop(hctx, msgs, errs, ended, h, commit)
+	err = errors.Join(err, c.revoke(ctx, partitions))
+	close(finished)
+	return err
+}
+
+// feedPartition sends the messages of r to out as f admits them until ctx
+// is done. Whoever receives a message releases it.
+func feedPartition(ctx context.Context, r *kafkago.Reader, f *flow, out chan<- kafkago.Message) error {
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err == nil {
+			err = f.admit(ctx, m)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		select {
+		case out <- m:
+		case <-ctx.Done():
+			f.release()
+			return nil
+		}
+	}
+}
+
+// handleLoop passes messages to h until ctx is done, end is closed or a
+// partition fails. commit, when set, commits the next offset to read per
+// partition following the delivery semantics; pending commits are flushed
+// before it returns.
+func (c *Consumer) handleLoop(ctx context.Context, msgs <-chan kafkago.Message, errs <-chan error, end <-chan struct{}, h Handler, commit func(map[int]int64) error) error {
+	pending := map[int]int64{}
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if err := commit(pending); err != nil {
+			return err
+		}
+		clear(pending)
+		return nil
+	}
+
+	var tick <-chan time.Time
+	if commit != nil && c.cfg.commitInterval > 0 {
+		t := time.NewTicker(c.cfg.commitInterval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	count := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return flush()
+		case <-end:
+			return flush()
+		case err := <-errs:
+			return errors.Join(err, flush())
+		case <-tick:
+			if err := flush(); err != nil {
+				return err
+			}
+			count = 0
+		case m := <-msgs:
+			if commit == nil {
+				err := h(ctx, fromKafka(m))
+				c.flow.release()
+				if err != nil && !interrupted(ctx, err) {
+					return err
+				}
+				continue
+			}
+			if c.cfg.semantics == AtMostOnce {
+				if err := commit(map[int]int64{m.Partition: m.Offset + 1}); err != nil {
+					c.flow.release()
+					return err
+				}
+				err := h(ctx, fromKafka(m))
+				c.flow.release()
+				if err != nil && !interrupted(ctx, err) {
+					return err
+				}
+				continue
+			}
+
+			err := h(ctx, fromKafka(m))
+			c.flow.release()
+			if err != nil {
+				if interrupted(ctx, err) {
+					return flush()
+				}
+				// everything before m was handled, only m stays uncommitted
+				return errors.Join(err, flush())
+			}
+			pending[m.Partition] = m.Offset + 1
+			if count++; count >= c.cfg.commitBatch {
+				if err := flush(); err != nil {
+					return err
+				}
+				count = 0
+			}
+		}
+	}
+}
+
+// interrupted reports whether err only says that a handler gave up because
+// ctx was cancelled, which is how handling stops rather than a failure.
+func interrupted(ctx context.Context, err error) bool {
+	return ctx.Err() != nil && errors.Is(err, context.Canceled)
+}
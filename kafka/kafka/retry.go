@@ -0,0 +1,350 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Headers added to messages forwarded to retry and dead-letter topics.
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderError             = "x-error"
+	HeaderAttempts          = "x-attempts"
+	// HeaderRetryAt is the unix time in milliseconds after which a message
+	// in a retry topic may be handled again.
+	HeaderRetryAt = "x-retry-at"
+)
+
+func RetryTopic(topic string, n int) string {
+	return fmt.Sprintf("%s.retry.%d", topic, n)
+}
+
+func DLQTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+// retryGroup is the group consuming <topic>.retry.n. Each retry topic has
+// its own so that joins of one don't rebalance the others.
+func retryGroup(group string, n int) string {
+	return fmt.Sprintf("%s.retry.%d", group, n)
+}
+
+// WithRetryDelay makes a consumer hold back messages carrying
+// HeaderRetryAt until they are due. Only their partition waits; the handler
+// is not blocked, so rebalances and the other partitions go on. Consumers
+// of retry topics need it.
+func WithRetryDelay() Option {
+	return func(c *config) { c.retryDelay = true }
+}
+
+// RetryPolicy describes how failing messages move through the pipeline.
+type RetryPolicy struct {
+	// Attempts is how many times a message is handled in-process at each
+	// stage before it is forwarded.
+	Attempts int
+	// Backoff is the pause before the second in-process attempt; it
+	// doubles for every further attempt.
+	Backoff time.Duration
+	// Delays holds one entry per retry topic: a message forwarded to
+	// <topic>.retry.N is handled no sooner than Delays[N-1] later. Messages
+	// failing the last retry topic go to <topic>.dlq.
+	Delays []time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts: 3,
+	Backoff:  100 * time.Millisecond,
+	Delays:   []time.Duration{time.Second, 30 * time.Second, 5 * time.Minute},
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying; the message goes straight to
+// the dead-letter topic.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+func isPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// FailurePipeline wraps a handler so that messages it fails on are retried
+// in-process, then through delayed retry topics, and finally parked in a
+// dead-letter topic, instead of stopping the consumer.
+type FailurePipeline struct {
+	topic   string
+	h       Handler
+	policy  RetryPolicy
+	opts    []Option
+	started time.Time
+
+	mu        sync.Mutex
+	producers map[string]*Producer
+}
+
+// NewFailurePipeline builds a pipeline for topic. opts configure the
+// producers used to forward messages and the consumers started by Run.
+func NewFailurePipeline(topic string, h Handler, policy RetryPolicy, opts ...Option) *FailurePipeline {
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+	return &FailurePipeline{
+		topic:     topic,
+		h:         h,
+		policy:    policy,
+		opts:      opts,
+		producers: map[string]*Producer{},
+	}
+}
+
+// Topics lists the retry topics followed by the dead-letter topic.
+func (f *FailurePipeline) Topics() []string {
+	var out []string
+	for i := range f.policy.Delays {
+		out = append(out, RetryTopic(f.topic, i+1))
+	}
+	return append(out, DLQTopic(f.topic))
+}
+
+// Handler is used to consume the main topic.
+func (f *FailurePipeline) Handler() Handler {
+	return f.stage(0)
+}
+
+// RetryHandler is used to consume <topic>.retry.n, with a consumer that
+// has WithRetryDelay.
+func (f *FailurePipeline) RetryHandler(n int) Handler {
+	return f.stage(n)
+}
+
+func (f *FailurePipeline) stage(n int) Handler {
+	return func(ctx context.Context, m Message) error {
+		err := f.attempt(ctx, m)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			// shutting down, leave the message uncommitted
+			return ctx.Err()
+		}
+
+		next := DLQTopic(f.topic)
+		var delay time.Duration
+		if n < len(f.policy.Delays) && !isPermanent(err) {
+			next = RetryTopic(f.topic, n+1)
+			delay = f.policy.Delays[n]
+		}
+		return f.forward(ctx, next, m, err, delay)
+	}
+}
+
+func (f *FailurePipeline) attempt(ctx context.Context, m Message) error {
+	backoff := f.policy.Backoff
+	var err error
+	for i := 0; i < f.policy.Attempts; i++ {
+		if i > 0 && backoff > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		if err = f.h(ctx, m); err == nil || isPermanent(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (f *FailurePipeline) forward(ctx context.Context, topic string, m Message, cause error, delay time.Duration) error {
+	p, err := f.producer(topic)
+	if err != nil {
+		return err
+	}
+
+	attempts := f.policy.Attempts
+	if v, ok := m.Header(HeaderAttempts); ok {
+		prev, _ := strconv.Atoi(string(v))
+		attempts += prev
+	}
+
+	out := Message{
+		Key:     m.Key,
+		Value:   m.Value,
+		Headers: append([]Header(nil), m.Headers...),
+	}
+	// only the first failure knows where the message came from
+	if _, ok := m.Header(HeaderOriginalTopic); !ok {
+		out.SetHeader(HeaderOriginalTopic, []byte(m.Topic))
+		out.SetHeader(HeaderOriginalPartition, []byte(strconv.Itoa(m.Partition)))
+		out.SetHeader(HeaderOriginalOffset, []byte(strconv.FormatInt(m.Offset, 10)))
+	}
+	out.SetHeader(HeaderError, []byte(cause.Error()))
+	out.SetHeader(HeaderAttempts, []byte(strconv.Itoa(attempts)))
+	if delay > 0 {
+		due := time.Now().Add(delay).UnixMilli()
+		out.SetHeader(HeaderRetryAt, []byte(strconv.FormatInt(due, 10)))
+	}
+
+	if err := p.Send(ctx, out); err != nil {
+		return fmt.Errorf("forwarding to %v: %w", topic, err)
+	}
+	return nil
+}
+
+func (f *FailurePipeline) producer(topic string) (*Producer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if p, ok := f.producers[topic]; ok {
+		return p, nil
+	}
+	opts := append(append([]Option(nil), f.opts...), WithTopic(topic))
+	p, err := NewProducer(opts...)
+	if err != nil {
+		return nil, err
+	}
+	f.producers[topic] = p
+	return p, nil
+}
+
+// waitUntilDue waits until a retried message's delay has passed or ctx is
+// done. Retry topics hold messages in the order they became due, so holding
+// back the partition does not hold back anything that is already due.
+func waitUntilDue(ctx context.Context, m kafkago.Message) error {
+	var due time.Time
+	for _, h := range m.Headers {
+		if h.Key != HeaderRetryAt {
+			continue
+		}
+		if ms, err := strconv.ParseInt(string(h.Value), 10, 64); err == nil {
+			due = time.UnixMilli(ms)
+		}
+	}
+	wait := time.Until(due)
+	if due.IsZero() || wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// EnsureTopics creates the retry and dead-letter topics that don't exist,
+// with as many partitions as the main topic so keys keep their order.
+func (f *FailurePipeline) EnsureTopics(ctx context.Context) error {
+	admin, err := NewAdmin(f.opts...)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	partitions := 0
+	info, err := admin.DescribeTopic(ctx, f.topic)
+	switch {
+	case err == nil:
+		partitions = len(info.Partitions)
+	case !errors.Is(err, ErrUnknownTopic):
+		return err
+	}
+	for _, t := range f.Topics() {
+		// existing topics are left as they are
+		if _, err := admin.DescribeTopic(ctx, t); !errors.Is(err, ErrUnknownTopic) {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err := admin.EnsureTopic(ctx, TopicSpec{Name: t, Partitions: partitions}); err != nil {
+			return fmt.Errorf("creating %v: %w", t, err)
+		}
+	}
+	return nil
+}
+
+// Run ensures the pipeline's topics, then consumes the main topic and every
+// retry topic with the pipeline's options until ctx is cancelled or one of
+// the consumers fails. Retry topics are consumed by groups of their own,
+// named <group>.retry.N.
+func (f *FailurePipeline) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := f.EnsureTopics(ctx); err != nil {
+		return err
+	}
+
+	topics := []string{f.topic}
+	for i := range f.policy.Delays {
+		topics = append(topics, RetryTopic(f.topic, i+1))
+	}
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		rerr error
+	)
+	var cfg config
+	for _, o := range f.opts {
+		o(&cfg)
+	}
+	for n, topic := range topics {
+		opts := append(append([]Option(nil), f.opts...), WithTopic(topic))
+		if n > 0 {
+			opts = append(opts, WithRetryDelay())
+			if cfg.group != "" {
+				opts = append(opts, WithGroup(retryGroup(cfg.group, n)))
+			}
+		}
+		c, err := NewConsumer(opts...)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			if err := c.Run(ctx, f.stage(n)); err != nil {
+				once.Do(func() {
+					rerr = err
+					cancel()
+				})
+			}
+		}(n)
+	}
+	wg.Wait()
+	return rerr
+}
+
+// Close releases the forwarding producers.
+func (f *FailurePipeline) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var errs []error
+	for topic, p := range f.producers {
+		errs = append(errs, p.Close())
+		delete(f.producers, topic)
+	}
+	return errors.Join(errs...)
+}
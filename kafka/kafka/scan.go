@@ -0,0 +1,178 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// ScanRange selects the records a Scan reads. Zero values leave a bound
+// open: from the start of the log, up to the high watermark at the time
+// the scan starts.
+type ScanRange struct {
+	// Partitions limits the scan to these partitions.
+	Partitions []int
+	// FromOffset is inclusive, ToOffset exclusive.
+	FromOffset int64
+	ToOffset   int64
+	// FromTime is inclusive, ToTime exclusive.
+	FromTime time.Time
+	ToTime   time.Time
+}
+
+// Scan reads the range of the topic set in opts, one partition after
+// another in partition order, and passes every record to fn. Records
+// produced after the scan started are not included.
+func Scan(ctx context.Context, rng ScanRange, fn func(Message) error, opts ...Option) error {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return err
+	}
+	admin := newAdmin(cfg)
+	defer admin.Close()
+
+	marks, err := admin.watermarks(ctx, map[string]bool{cfg.topic: true})
+	if err != nil {
+		return err
+	}
+	if len(marks) == 0 {
+		return fmt.Errorf("%w: %v", ErrUnknownTopic, cfg.topic)
+	}
+
+	var from, to map[int]int64
+	if !rng.FromTime.IsZero() {
+		if from, err = admin.offsetsAt(ctx, cfg.topic, marks, rng.FromTime); err != nil {
+			return err
+		}
+	}
+	if !rng.ToTime.IsZero() {
+		if to, err = admin.offsetsAt(ctx, cfg.topic, marks, rng.ToTime); err != nil {
+			return err
+		}
+	}
+
+	partitions := rng.Partitions
+	if len(partitions) == 0 {
+		for tp := range marks {
+			partitions = append(partitions, tp.partition)
+		}
+		sort.Ints(partitions)
+	}
+	for _, p := range partitions {
+		w, ok := marks[topicPartition{cfg.topic, p}]
+		if !ok {
+			return fmt.Errorf("topic %v has no partition %v", cfg.topic, p)
+		}
+		start, end := max(w.first, rng.FromOffset), w.last
+		if rng.ToOffset > 0 {
+			end = min(end, rng.ToOffset)
+		}
+		if off, ok := from[p]; ok {
+			start = max(start, off)
+		}
+		if off, ok := to[p]; ok {
+			end = min(end, off)
+		}
+		if start >= end {
+			continue
+		}
+		if err := scanPartition(ctx, cfg, admin, p, start, end, fn); err != nil {
+			return fmt.Errorf("scanning %v/%v: %w", cfg.topic, p, err)
+		}
+	}
+	return nil
+}
+
+func scanPartition(ctx context.Context, cfg config, admin *Admin, partition int, start, end int64, fn func(Message) error) error {
+	r := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:          cfg.brokers,
+		Topic:            cfg.topic,
+		Partition:        partition,
+		MaxBytes:         cfg.maxBytes,
+		ReadBatchTimeout: cfg.readTimeout,
+		Dialer:           cfg.dialer(),
+	})
+	defer r.Close()
+	if err := r.SetOffset(start); err != nil {
+		return err
+	}
+
+	next := start
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, cfg.readTimeout)
+		m, err := r.FetchMessage(fetchCtx)
+		expired := fetchCtx.Err() != nil
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && expired {
+				return scanStalled(ctx, cfg, admin, partition, next, end)
+			}
+			return err
+		}
+		if m.Offset >= end {
+			return nil
+		}
+		if err := fn(fromKafka(m)); err != nil {
+			return err
+		}
+		next = m.Offset + 1
+		if next >= end {
+			return nil
+		}
+	}
+}
+
+// scanStalled decides what a fetch that returned nothing for readTimeout
+// means for a scan that still expects offsets next up to end. The scan is
+// complete only if the partition's watermarks show those offsets are gone,
+// deleted by retention or truncated away, or if they hold no records but
+// transaction markers; otherwise the broker stopped delivering and the scan
+// would be short.
+func scanStalled(ctx context.Context, cfg config, admin *Admin, partition int, next, end int64) error {
+	marks, err := admin.watermarks(ctx, map[string]bool{cfg.topic: true})
+	if err != nil {
+		return fmt.Errorf("no records from offset %v within %v, re-reading watermarks: %w", next, cfg.readTimeout, err)
+	}
+	w, ok := marks[topicPartition{cfg.topic, partition}]
+	if ok && (w.first >= end || w.last <= next) {
+		return nil
+	}
+	// transaction markers take offsets but are never delivered, so the
+	// offsets left may hold nothing a reader returns
+	if more, err := admin.recordsBetween(ctx, cfg.topic, partition, next, end); err == nil && !more {
+		return nil
+	}
+	return fmt.Errorf("no records from offset %v within %v, %v offsets up to %v remain", next, cfg.readTimeout, end-next, end)
+}
+
+// recordsBetween reports whether the partition holds records other than
+// transaction markers from offset next up to end.
+func (a *Admin) recordsBetween(ctx context.Context, topic string, partition int, next, end int64) (bool, error) {
+	resp, err := a.client.Fetch(ctx, &kafkago.FetchRequest{
+		Topic:     topic,
+		Partition: partition,
+		Offset:    next,
+		MinBytes:  1,
+		MaxBytes:  int64(a.cfg.maxBytes),
+	})
+	if err != nil {
+		return false, err
+	}
+	if resp.Error != nil {
+		return false, resp.Error
+	}
+	// control batches are skipped, so the first record is a real one
+	r, err := resp.Records.ReadRecord()
+	switch {
+	case errors.Is(err, io.EOF):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return r.Offset < end, nil
+}
@@ -0,0 +1,156 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// Environment variables read by every Producer, Consumer and Admin before
+// their options are applied, so options override them. Setting any of the
+// file variables or kafka_tls=true enables TLS.
+const (
+	EnvTLS           = "kafka_tls"
+	EnvCAFile        = "kafka_ca_file"
+	EnvCertFile      = "kafka_cert_file"
+	EnvKeyFile       = "kafka_key_file"
+	EnvSASLMechanism = "kafka_sasl_mechanism"
+	EnvUsername      = "kafka_username"
+	EnvPassword      = "kafka_password"
+)
+
+// SASLMechanism is a SASL authentication mechanism.
+type SASLMechanism string
+
+const (
+	SASLPlain       SASLMechanism = "PLAIN"
+	SASLScramSHA256 SASLMechanism = "SCRAM-SHA-256"
+	SASLScramSHA512 SASLMechanism = "SCRAM-SHA-512"
+)
+
+// ParseSASLMechanism returns the mechanism named s, ignoring case.
+func ParseSASLMechanism(s string) (SASLMechanism, error) {
+	switch m := SASLMechanism(strings.ToUpper(s)); m {
+	case SASLPlain, SASLScramSHA256, SASLScramSHA512:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown SASL mechanism %q", s)
+}
+
+// WithTLS encrypts connections to the brokers with cfg. A nil cfg uses
+// the system roots.
+func WithTLS(cfg *tls.Config) Option {
+	return func(c *config) {
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		c.tls = cfg
+	}
+}
+
+// WithTLSFiles encrypts connections and trusts the PEM CA certificates in
+// caFile, or the system roots when it is empty. certFile and keyFile hold
+// the client certificate for brokers that authenticate clients with TLS;
+// both may be empty.
+func WithTLSFiles(caFile, certFile, keyFile string) Option {
+	return func(c *config) {
+		cfg, err := loadTLS(caFile, certFile, keyFile)
+		if err != nil {
+			c.err = errors.Join(c.err, err)
+			return
+		}
+		c.tls = cfg
+	}
+}
+
+// WithSASL authenticates with mechanism m. It is usually combined with
+// TLS since PLAIN sends the password as is.
+func WithSASL(m SASLMechanism, username, password string) Option {
+	return func(c *config) {
+		mech, err := m.mechanism(username, password)
+		if err != nil {
+			c.err = errors.Join(c.err, err)
+			return
+		}
+		c.sasl = mech
+	}
+}
+
+func (m SASLMechanism) mechanism(username, password string) (sasl.Mechanism, error) {
+	switch m {
+	case SASLPlain:
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case SASLScramSHA256:
+		return scram.Mechanism(scram.SHA256, username, password)
+	case SASLScramSHA512:
+		return scram.Mechanism(scram.SHA512, username, password)
+	}
+	return nil, fmt.Errorf("unknown SASL mechanism %q", string(m))
+}
+
+func loadTLS(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in CA file %v", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// envSecurity returns the options described by the environment variables.
+func envSecurity() []Option {
+	var opts []Option
+	ca, cert, key := os.Getenv(EnvCAFile), os.Getenv(EnvCertFile), os.Getenv(EnvKeyFile)
+	if ca != "" || cert != "" || key != "" || strings.EqualFold(os.Getenv(EnvTLS), "true") {
+		opts = append(opts, WithTLSFiles(ca, cert, key))
+	}
+	if s := os.Getenv(EnvSASLMechanism); s != "" {
+		m, err := ParseSASLMechanism(s)
+		if err != nil {
+			return append(opts, func(c *config) { c.err = errors.Join(c.err, err) })
+		}
+		opts = append(opts, WithSASL(m, os.Getenv(EnvUsername), os.Getenv(EnvPassword)))
+	}
+	return opts
+}
+
+// dialer returns the dialer used by readers.
+func (c config) dialer() *kafkago.Dialer {
+	return &kafkago.Dialer{
+		ClientID:      c.clientID,
+		Timeout:       c.dialTimeout,
+		TLS:           c.tls,
+		SASLMechanism: c.sasl,
+	}
+}
+
+// transport returns the transport used by writers and the admin client.
+func (c config) transport() *kafkago.Transport {
+	return &kafkago.Transport{
+		ClientID:    c.clientID,
+		DialTimeout: c.dialTimeout,
+		TLS:         c.tls,
+		SASL:        c.sasl,
+	}
+}
@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+)
+
+// Semantics decides when a group consumer commits a message's offset
+// relative to handling it.
+type Semantics int
+
+const (
+	// AtLeastOnce commits after the handler succeeds.
+	AtLeastOnce Semantics = iota
+	// AtMostOnce commits before the handler runs.
+	AtMostOnce
+)
+
+func ParseSemantics(s string) (Semantics, error) {
+	switch s {
+	case "at-least-once":
+		return AtLeastOnce, nil
+	case "at-most-once":
+		return AtMostOnce, nil
+	}
+	return 0, fmt.Errorf("kafka: unknown delivery semantics %q", s)
+}
+
+func (s Semantics) String() string {
+	switch s {
+	case AtLeastOnce:
+		return "at-least-once"
+	case AtMostOnce:
+		return "at-most-once"
+	}
+	return fmt.Sprintf("Semantics(%d)", int(s))
+}
+
+func WithSemantics(s Semantics) Option {
+	return func(c *config) { c.semantics = s }
+}
+
+// WithCommitBatch commits at-least-once offsets after every n handled
+// messages instead of after each one.
+func WithCommitBatch(n int) Option {
+	return func(c *config) { c.commitBatch = n }
+}
+
+// WithCommitInterval commits handled offsets at least this often, even when
+// the commit batch is not full.
+func WithCommitInterval(d time.Duration) Option {
+	return func(c *config) { c.commitInterval = d }
+}
@@ -0,0 +1,235 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"producer/store"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+const (
+	// tableBatch and tableFlushDelay bound how many records a table holds
+	// back before writing them to a durable store.
+	tableBatch      = 1000
+	tableFlushDelay = 100 * time.Millisecond
+)
+
+// Table materializes a compacted topic into a store: every key maps to its
+// newest value, and tombstones (records with a nil value) delete the key.
+// kafka-go reads an empty value as nil, so records with an empty value
+// delete their key as well. It reads every partition itself, without a
+// consumer group.
+type Table struct {
+	cfg   config
+	store store.Store
+
+	ready     chan struct{}
+	readyOnce sync.Once
+	remaining atomic.Int64
+}
+
+// NewTable builds a table over the topic set in opts. Durable stores let the
+// table resume from the offsets they recorded; other stores are filled from
+// the start of the topic.
+func NewTable(s store.Store, opts ...Option) (*Table, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Table{cfg: cfg, store: s, ready: make(chan struct{})}, nil
+}
+
+// Ready is closed once every partition has been read up to the high
+// watermark it had when Run first started. It stays closed when Run is
+// called again.
+func (t *Table) Ready() <-chan struct{} {
+	return t.ready
+}
+
+// WaitReady blocks until the table has caught up or ctx is done.
+func (t *Table) WaitReady(ctx context.Context) error {
+	select {
+	case <-t.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Table) Get(key []byte) ([]byte, bool, error) {
+	return t.store.Get(key)
+}
+
+// Range calls fn in key order for keys in [from, to); nil bounds are open.
+func (t *Table) Range(from, to []byte, fn func(key, value []byte) bool) error {
+	return t.store.Range(from, to, fn)
+}
+
+// Run keeps the store up to date until ctx is cancelled, which is not
+// reported as an error, or reading a partition fails.
+func (t *Table) Run(ctx context.Context) error {
+	admin := newAdmin(t.cfg)
+	defer admin.Close()
+	marks, err := admin.watermarks(ctx, map[string]bool{t.cfg.topic: true})
+	if err != nil {
+		return err
+	}
+	if len(marks) == 0 {
+		return fmt.Errorf("%w: %v", ErrUnknownTopic, t.cfg.topic)
+	}
+
+	durable, _ := t.store.(store.Durable)
+	starts := map[int]int64{}
+	t.remaining.Store(0)
+	for tp, w := range marks {
+		start := w.first
+		if durable != nil {
+			off, ok, err := durable.Offset(t.cfg.topic, tp.partition)
+			if err != nil {
+				return err
+			}
+			if ok && off > start {
+				start = off
+			}
+		}
+		starts[tp.partition] = start
+		if start < w.last {
+			t.remaining.Add(1)
+		}
+	}
+	if t.remaining.Load() == 0 {
+		t.markReady()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		rerr error
+	)
+	for tp, w := range marks {
+		wg.Add(1)
+		go func(partition int, start, hwm int64) {
+			defer wg.Done()
+			if err := t.readPartition(ctx, admin, durable, partition, start, hwm); err != nil {
+				once.Do(func() {
+					rerr = fmt.Errorf("reading %v/%v: %w", t.cfg.topic, partition, err)
+					cancel()
+				})
+			}
+		}(tp.partition, starts[tp.partition], w.last)
+	}
+	wg.Wait()
+	return rerr
+}
+
+func (t *Table) markReady() {
+	t.readyOnce.Do(func() { close(t.ready) })
+}
+
+func (t *Table) readPartition(ctx context.Context, admin *Admin, durable store.Durable, partition int, start, hwm int64) error {
+	r := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:          t.cfg.brokers,
+		Topic:            t.cfg.topic,
+		Partition:        partition,
+		MaxBytes:         t.cfg.maxBytes,
+		ReadBatchTimeout: t.cfg.readTimeout,
+		Dialer:           t.cfg.dialer(),
+	})
+	defer r.Close()
+	if err := r.SetOffset(start); err != nil {
+		return err
+	}
+
+	var batch []store.Change
+	next := start
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := durable.Apply(t.cfg.topic, partition, next, batch)
+		batch = batch[:0]
+		return err
+	}
+	caughtUp := start >= hwm
+	catchUp := func() error {
+		// everything read so far must be visible once Ready closes
+		if err := flush(); err != nil {
+			return err
+		}
+		caughtUp = true
+		if t.remaining.Add(-1) == 0 {
+			t.markReady()
+		}
+		return nil
+	}
+
+	for {
+		// with records held back, stop waiting for more after a short while
+		// and write them; while catching up, stop after readTimeout to see
+		// whether the offsets left hold anything at all
+		fetchCtx, cancel := ctx, context.CancelFunc(func() {})
+		stallCheck := false
+		switch {
+		case len(batch) > 0:
+			fetchCtx, cancel = context.WithTimeout(ctx, tableFlushDelay)
+		case !caughtUp:
+			fetchCtx, cancel = context.WithTimeout(ctx, t.cfg.readTimeout)
+			stallCheck = true
+		}
+		m, err := r.FetchMessage(fetchCtx)
+		expired := fetchCtx.Err() != nil
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return flush()
+			}
+			if !expired {
+				return err
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			// offsets up to hwm that are gone or only hold transaction
+			// markers never arrive; otherwise keep waiting for them
+			if stallCheck && scanStalled(ctx, t.cfg, admin, partition, next, hwm) == nil {
+				if err := catchUp(); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		next = m.Offset + 1
+
+		// records without a key cannot be placed in a table
+		if m.Key != nil {
+			if durable != nil {
+				batch = append(batch, store.Change{Key: m.Key, Value: m.Value})
+			} else if m.Value == nil {
+				err = t.store.Delete(m.Key)
+			} else {
+				err = t.store.Put(m.Key, m.Value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if len(batch) >= tableBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		if !caughtUp && next >= hwm {
+			if err := catchUp(); err != nil {
+				return err
+			}
+		}
+	}
+}
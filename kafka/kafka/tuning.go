@@ -0,0 +1,135 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/compress"
+)
+
+// Acks is how many replicas must acknowledge a write before it succeeds.
+type Acks int
+
+const (
+	AcksNone   Acks = 0
+	AcksLeader Acks = 1
+	AcksAll    Acks = -1
+)
+
+func ParseAcks(s string) (Acks, error) {
+	switch s {
+	case "none", "0":
+		return AcksNone, nil
+	case "leader", "1":
+		return AcksLeader, nil
+	case "all", "-1":
+		return AcksAll, nil
+	}
+	return 0, fmt.Errorf("kafka: unknown acks %q", s)
+}
+
+func (a Acks) String() string {
+	switch a {
+	case AcksNone:
+		return "none"
+	case AcksLeader:
+		return "leader"
+	case AcksAll:
+		return "all"
+	}
+	return fmt.Sprintf("Acks(%d)", int(a))
+}
+
+type Compression string
+
+const (
+	CompressionNone   Compression = "none"
+	CompressionGzip   Compression = "gzip"
+	CompressionSnappy Compression = "snappy"
+	CompressionLz4    Compression = "lz4"
+	CompressionZstd   Compression = "zstd"
+)
+
+func ParseCompression(s string) (Compression, error) {
+	c := Compression(s)
+	if s == "" {
+		c = CompressionNone
+	}
+	if _, err := c.codec(); err != nil {
+		return "", err
+	}
+	return c, nil
+}
+
+func (c Compression) codec() (kafkago.Compression, error) {
+	switch c {
+	case CompressionNone, "":
+		return 0, nil
+	case CompressionGzip:
+		return compress.Gzip, nil
+	case CompressionSnappy:
+		return compress.Snappy, nil
+	case CompressionLz4:
+		return compress.Lz4, nil
+	case CompressionZstd:
+		return compress.Zstd, nil
+	}
+	return 0, fmt.Errorf("kafka: unknown compression %q", string(c))
+}
+
+// DeliveryReport is the outcome of producing one message. On success the
+// message's Topic, Partition, Offset and Time are set from the broker's
+// response.
+type DeliveryReport struct {
+	Message Message
+	Err     error
+}
+
+// WithBatchSize caps how many messages are sent to a partition at once.
+func WithBatchSize(n int) Option {
+	return func(c *config) { c.batchSize = n }
+}
+
+// WithBatchBytes caps the size of a single produce request.
+func WithBatchBytes(n int64) Option {
+	return func(c *config) { c.batchBytes = n }
+}
+
+// WithLinger sets how long a producer waits for a batch to fill before
+// sending it anyway.
+func WithLinger(d time.Duration) Option {
+	return func(c *config) { c.linger = d }
+}
+
+func WithRequiredAcks(a Acks) Option {
+	return func(c *config) { c.acks = a }
+}
+
+// WithMaxAttempts sets how many times a failed write is tried in total.
+func WithMaxAttempts(n int) Option {
+	return func(c *config) { c.maxAttempts = n }
+}
+
+func WithCompression(codec Compression) Option {
+	return func(c *config) { c.compression = codec }
+}
+
+// WithAsync makes Send return as soon as messages are queued. Failures are
+// only visible through delivery reports.
+func WithAsync() Option {
+	return func(c *config) { c.async = true }
+}
+
+// WithDeliveryReports calls fn once per produced message, from the
+// producer's own goroutines. In synchronous mode Send returns only after
+// the reports for its messages were delivered.
+func WithDeliveryReports(fn func(DeliveryReport)) Option {
+	return func(c *config) { c.onDelivery = fn }
+}
+
+// WithDeliveryChannel sends a report per produced message to ch. The
+// producer blocks while ch is full, so it must be drained.
+func WithDeliveryChannel(ch chan<- DeliveryReport) Option {
+	return WithDeliveryReports(func(r DeliveryReport) { ch <- r })
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"producer/kafka"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// lagCommand runs the "lag" command.
+func lagCommand(ctx context.Context, args []string) error {
+	var (
+		brokers string
+		groups  string
+		watch   time.Duration
+		metrics string
+		jsonOut bool
+	)
+	fs := flag.NewFlagSet("lag", flag.ExitOnError)
+	fs.StringVar(&brokers, "brokers", url, "comma separated broker addresses")
+	fs.StringVar(&groups, "group", "", "comma separated consumer groups, all groups if empty")
+	fs.DurationVar(&watch, "watch", 0, "refresh interval, 0 to report once")
+	fs.StringVar(&metrics, "metrics", "", "serve lag in Prometheus text format on this address, e.g. :9308")
+	fs.BoolVar(&jsonOut, "json", false, "print JSON instead of tables")
+	fs.Parse(args)
+
+	admin, err := kafka.NewAdmin(kafka.WithBrokers(strings.Split(brokers, ",")...))
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	collect := func() ([]kafka.GroupLag, error) {
+		return groupLags(ctx, admin, groups)
+	}
+
+	if metrics == "" {
+		for {
+			lags, err := collect()
+			if err != nil {
+				return err
+			}
+			if jsonOut {
+				if err := json.NewEncoder(os.Stdout).Encode(lags); err != nil {
+					return err
+				}
+			} else {
+				printLag(os.Stdout, lags)
+			}
+			if watch <= 0 {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(watch):
+			}
+		}
+	}
+
+	if watch <= 0 {
+		watch = 15 * time.Second
+	}
+	exp := &lagExporter{}
+	go func() {
+		for {
+			lags, err := collect()
+			if err != nil {
+				log.Printf("collecting lag: %v", err)
+			}
+			exp.set(lags, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watch):
+			}
+		}
+	}()
+
+	srv := &http.Server{Addr: metrics, Handler: exp}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	log.Printf("serving lag metrics on %v/metrics", metrics)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func groupLags(ctx context.Context, admin *kafka.Admin, groups string) ([]kafka.GroupLag, error) {
+	var ids []string
+	if groups != "" {
+		ids = strings.Split(groups, ",")
+	} else {
+		all, err := admin.ListGroups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range all {
+			ids = append(ids, g.ID)
+		}
+	}
+
+	lags := make([]kafka.GroupLag, 0, len(ids))
+	for _, id := range ids {
+		lag, err := admin.GroupLag(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		lags = append(lags, lag)
+	}
+	return lags, nil
+}
+
+func printLag(w io.Writer, lags []kafka.GroupLag) {
+	for _, g := range lags {
+		fmt.Fprintf(w, "group %v (%v), %v members, total lag %v\n\n", g.ID, g.State, len(g.Members), g.Total)
+
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "TOPIC\tPARTITION\tCOMMITTED\tHIGH WATERMARK\tLAG\tMEMBER")
+		for _, p := range g.Partitions {
+			committed := fmt.Sprint(p.Committed)
+			if p.Committed < 0 {
+				committed = "-"
+			}
+			member := "-"
+			for _, m := range g.Members {
+				if m.ID == p.Member {
+					member = m.ClientID + "/" + m.Host
+				}
+			}
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\n", p.Topic, p.Partition, committed, p.HighWatermark, p.Lag, member)
+		}
+		tw.Flush()
+		fmt.Fprintln(w)
+	}
+}
+
+// lagExporter serves the last collected lag in the Prometheus text format.
+type lagExporter struct {
+	mu   sync.Mutex
+	lags []kafka.GroupLag
+	err  error
+	at   time.Time
+}
+
+func (e *lagExporter) set(lags []kafka.GroupLag, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err == nil {
+		e.lags = lags
+		e.at = time.Now()
+	}
+	e.err = err
+}
+
+func (e *lagExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/metrics" {
+		http.NotFound(w, r)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	up := 1
+	if e.err != nil {
+		up = 0
+	}
+	fmt.Fprintf(w, "# HELP kafka_lag_exporter_up Whether the last collection succeeded.\n# TYPE kafka_lag_exporter_up gauge\nkafka_lag_exporter_up %v\n", up)
+	if !e.at.IsZero() {
+		fmt.Fprintf(w, "# HELP kafka_lag_exporter_last_success_seconds Unix time of the last successful collection.\n# TYPE kafka_lag_exporter_last_success_seconds gauge\nkafka_lag_exporter_last_success_seconds %v\n", e.at.Unix())
+	}
+
+	gauge := func(name, help string, each func(g kafka.GroupLag, emit func(labels string, v int64))) {
+		fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v gauge\n", name, help, name)
+		for _, g := range e.lags {
+			each(g, func(labels string, v int64) {
+				fmt.Fprintf(w, "%v{%v} %v\n", name, labels, v)
+			})
+		}
+	}
+	gauge("kafka_consumergroup_members", "Members of the consumer group.", func(g kafka.GroupLag, emit func(string, int64)) {
+		emit(fmt.Sprintf("group=%q", g.ID), int64(len(g.Members)))
+	})
+	gauge("kafka_consumergroup_lag_total", "Messages the group is behind across all partitions.", func(g kafka.GroupLag, emit func(string, int64)) {
+		emit(fmt.Sprintf("group=%q", g.ID), g.Total)
+	})
+	gauge("kafka_consumergroup_lag", "Messages the group is behind on a partition.", func(g kafka.GroupLag, emit func(string, int64)) {
+		for _, p := range g.Partitions {
+			emit(partitionLabels(g.ID, p), p.Lag)
+		}
+	})
+	gauge("kafka_consumergroup_committed_offset", "Offset the group last committed on a partition.", func(g kafka.GroupLag, emit func(string, int64)) {
+		for _, p := range g.Partitions {
+			if p.Committed >= 0 {
+				emit(partitionLabels(g.ID, p), p.Committed)
+			}
+		}
+	})
+	gauge("kafka_partition_high_watermark", "High watermark of a partition the group reads.", func(g kafka.GroupLag, emit func(string, int64)) {
+		for _, p := range g.Partitions {
+			emit(partitionLabels(g.ID, p), p.HighWatermark)
+		}
+	})
+}
+
+func partitionLabels(group string, p kafka.PartitionLag) string {
+	return fmt.Sprintf("group=%q,topic=%q,partition=\"%v\"", group, p.Topic, p.Partition)
+}
@@ -0,0 +1,530 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"producer/kafka"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// Every load test value starts with the run ID and the time it was
+// produced, so consumers can skip foreign messages and measure latency.
+const loadTestPrefix = 16
+
+type loadTestConfig struct {
+	Brokers     []string
+	Topic       string
+	Partitions  int
+	Replication int
+
+	Producers int
+	Messages  int
+	Duration  time.Duration
+	Size      int
+	Keys      int
+	KeyDist   string
+
+	BatchSize   int
+	BatchBytes  int64
+	Linger      time.Duration
+	Acks        string
+	Compression string
+	Partitioner string
+
+	Consumers int
+	Group     string
+	// Warmup gives the consumer group time to join and get assignments
+	// before anything is produced.
+	Warmup time.Duration
+	// Drain is how long consumers keep reading after producers finish.
+	Drain time.Duration
+}
+
+type partitionLoad struct {
+	Partition int   `json:"partition"`
+	Produced  int64 `json:"produced"`
+	Consumed  int64 `json:"consumed"`
+}
+
+type loadTestReport struct {
+	Topic       string `json:"topic"`
+	Partitions  int    `json:"partitions"`
+	Producers   int    `json:"producers"`
+	Consumers   int    `json:"consumers"`
+	Group       string `json:"group"`
+	Size        int    `json:"size"`
+	Acks        string `json:"acks"`
+	Compression string `json:"compression"`
+	Partitioner string `json:"partitioner"`
+	BatchSize   int    `json:"batch_size"`
+
+	ProduceElapsed time.Duration `json:"produce_elapsed"`
+	ConsumeElapsed time.Duration `json:"consume_elapsed"`
+
+	Produced      int64   `json:"produced"`
+	ProduceErrors int64   `json:"produce_errors"`
+	Consumed      int64   `json:"consumed"`
+	ProduceRate   float64 `json:"produce_rate"`
+	ProduceMBps   float64 `json:"produce_mbps"`
+	ConsumeRate   float64 `json:"consume_rate"`
+	ConsumeMBps   float64 `json:"consume_mbps"`
+
+	PerPartition []partitionLoad `json:"per_partition"`
+	// ProduceSkew and ConsumeSkew are the busiest partition's share relative
+	// to an even spread; 1 means perfectly balanced.
+	ProduceSkew float64 `json:"produce_skew"`
+	ConsumeSkew float64 `json:"consume_skew"`
+
+	ProduceLatency  latencySummary `json:"produce_latency"`
+	EndToEndLatency latencySummary `json:"end_to_end_latency"`
+}
+
+// loadTest runs the "loadtest" command.
+func loadTest(ctx context.Context, args []string) error {
+	var (
+		cfg     loadTestConfig
+		brokers string
+		jsonOut string
+	)
+	fs := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	fs.StringVar(&brokers, "brokers", url, "comma separated broker addresses")
+	fs.StringVar(&cfg.Topic, "topic", topic, "topic to load")
+	fs.IntVar(&cfg.Partitions, "partitions", 0, "create the topic, or grow it, to this many partitions")
+	fs.IntVar(&cfg.Replication, "replication", 1, "replication factor when creating the topic")
+	fs.IntVar(&cfg.Producers, "producers", 1, "number of producers")
+	fs.IntVar(&cfg.Messages, "messages", 0, "messages per producer, 0 to produce for -duration")
+	fs.DurationVar(&cfg.Duration, "duration", 30*time.Second, "how long to produce when -messages is 0")
+	fs.IntVar(&cfg.Size, "size", 1024, "message value size in bytes")
+	fs.IntVar(&cfg.Keys, "keys", 100, "number of distinct keys, 0 for null keys")
+	fs.StringVar(&cfg.KeyDist, "key-dist", "uniform", "key distribution: uniform, sequential or zipf")
+	fs.IntVar(&cfg.BatchSize, "batch-size", 100, "messages per produce batch")
+	fs.Int64Var(&cfg.BatchBytes, "batch-bytes", 1048576, "max bytes per produce batch")
+	fs.DurationVar(&cfg.Linger, "linger", 10*time.Millisecond, "how long to wait to fill a batch")
+	fs.StringVar(&cfg.Acks, "acks", "all", "required acks: none, leader or all")
+	fs.StringVar(&cfg.Compression, "compression", "none", "none, gzip, snappy, lz4 or zstd")
+	fs.StringVar(&cfg.Partitioner, "partitioner", "hash", "hash, murmur2, crc32, round-robin, least-bytes or sticky")
+	fs.IntVar(&cfg.Consumers, "consumers", 1, "number of consumers in the group")
+	fs.StringVar(&cfg.Group, "group", "", "consumer group, defaults to a fresh group per run")
+	fs.DurationVar(&cfg.Warmup, "warmup", 10*time.Second, "time for consumers to join before producing")
+	fs.DurationVar(&cfg.Drain, "drain", 10*time.Second, "max time to keep consuming after producing stops")
+	fs.StringVar(&jsonOut, "json", "", "also write the report as JSON to this file, - for stdout")
+	fs.Parse(args)
+
+	cfg.Brokers = strings.Split(brokers, ",")
+
+	report, err := runLoadTest(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	if jsonOut == "-" {
+		return report.writeJSON(os.Stdout)
+	}
+	if err := report.writeTable(os.Stdout); err != nil {
+		return err
+	}
+	if jsonOut != "" {
+		f, err := os.Create(jsonOut)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return report.writeJSON(f)
+	}
+	return nil
+}
+
+func runLoadTest(ctx context.Context, cfg loadTestConfig) (loadTestReport, error) {
+	acks, err := kafka.ParseAcks(cfg.Acks)
+	if err != nil {
+		return loadTestReport{}, err
+	}
+	codec, err := kafka.ParseCompression(cfg.Compression)
+	if err != nil {
+		return loadTestReport{}, err
+	}
+	partitioner, err := kafka.ParsePartitioner(cfg.Partitioner)
+	if err != nil {
+		return loadTestReport{}, err
+	}
+	switch cfg.KeyDist {
+	case "uniform", "sequential", "zipf":
+	default:
+		return loadTestReport{}, fmt.Errorf("unknown key distribution %q", cfg.KeyDist)
+	}
+	if cfg.Size < loadTestPrefix || cfg.Producers < 1 {
+		return loadTestReport{}, fmt.Errorf("need at least one producer and a message size of %v bytes or more", loadTestPrefix)
+	}
+
+	partitions, err := ensurePartitions(ctx, cfg)
+	if err != nil {
+		return loadTestReport{}, err
+	}
+
+	runID := uint64(time.Now().UnixNano())
+	if cfg.Group == "" {
+		cfg.Group = fmt.Sprintf("loadtest-%x", runID)
+	}
+
+	produced := make([]atomic.Int64, partitions)
+	consumed := make([]atomic.Int64, partitions)
+	var (
+		acked         atomic.Int64
+		produceErrors atomic.Int64
+		total         atomic.Int64
+		produceLat    histogram
+		endToEndLat   histogram
+	)
+
+	// consumers
+	if cfg.Consumers > 0 {
+		if err := startAtEnd(ctx, cfg); err != nil {
+			return loadTestReport{}, err
+		}
+	}
+	consumeCtx, stopConsumers := context.WithCancel(ctx)
+	defer stopConsumers()
+	var cwg sync.WaitGroup
+	consumeErr := make(chan error, cfg.Consumers)
+	var firstConsumed, lastConsumed atomic.Int64
+	for i := 0; i < cfg.Consumers; i++ {
+		cwg.Add(1)
+		go func() {
+			defer cwg.Done()
+			c, err := kafka.NewConsumer(
+				kafka.WithBrokers(cfg.Brokers...),
+				kafka.WithTopic(cfg.Topic),
+				kafka.WithGroup(cfg.Group),
+				kafka.WithMaxBytes(10e6),
+				kafka.WithCommitInterval(time.Second),
+				kafka.WithCommitBatch(100),
+			)
+			if err == nil {
+				defer c.Close()
+				err = c.Run(consumeCtx, func(ctx context.Context, m kafka.Message) error {
+					sent, ok := loadTestSent(m.Value, runID)
+					if !ok {
+						return nil
+					}
+					now := time.Now()
+					endToEndLat.add(now.Sub(sent))
+					if m.Partition < partitions {
+						consumed[m.Partition].Add(1)
+					}
+					firstConsumed.CompareAndSwap(0, now.UnixNano())
+					lastConsumed.Store(now.UnixNano())
+					total.Add(1)
+					return nil
+				})
+			}
+			if err != nil && consumeCtx.Err() == nil {
+				consumeErr <- err
+			}
+		}()
+	}
+	if cfg.Consumers > 0 {
+		select {
+		case <-time.After(cfg.Warmup):
+		case <-ctx.Done():
+			return loadTestReport{}, ctx.Err()
+		case err := <-consumeErr:
+			return loadTestReport{}, err
+		}
+	}
+
+	// producers
+	produceCtx := ctx
+	if cfg.Messages == 0 {
+		var cancel context.CancelFunc
+		produceCtx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+	producers := make([]*kafka.Producer, cfg.Producers)
+	for i := range producers {
+		p, err := kafka.NewProducer(
+			kafka.WithBrokers(cfg.Brokers...),
+			kafka.WithTopic(cfg.Topic),
+			kafka.WithBatchSize(cfg.BatchSize),
+			kafka.WithBatchBytes(cfg.BatchBytes),
+			kafka.WithLinger(cfg.Linger),
+			kafka.WithRequiredAcks(acks),
+			kafka.WithCompression(codec),
+			kafka.WithPartitioner(partitioner),
+			kafka.WithDeliveryReports(func(r kafka.DeliveryReport) {
+				if r.Err == nil && r.Message.Partition < partitions {
+					produced[r.Message.Partition].Add(1)
+				}
+			}),
+		)
+		if err != nil {
+			return loadTestReport{}, err
+		}
+		defer p.Close()
+		producers[i] = p
+	}
+
+	var pwg sync.WaitGroup
+	start := time.Now()
+	for i, p := range producers {
+		pwg.Add(1)
+		go func(id int, p *kafka.Producer) {
+			defer pwg.Done()
+
+			keys := newKeyGen(cfg.KeyDist, cfg.Keys, int64(id))
+			batch := make([]kafka.Message, 0, cfg.BatchSize)
+			for n := 0; cfg.Messages == 0 || n < cfg.Messages; n += len(batch) {
+				if produceCtx.Err() != nil {
+					return
+				}
+				batch = batch[:0]
+				now := time.Now()
+				for j := 0; j < cfg.BatchSize && (cfg.Messages == 0 || n+j < cfg.Messages); j++ {
+					value := make([]byte, cfg.Size)
+					binary.BigEndian.PutUint64(value, runID)
+					binary.BigEndian.PutUint64(value[8:], uint64(now.UnixNano()))
+					batch = append(batch, kafka.Message{
+						Key:   keys.next(),
+						Value: value,
+					})
+				}
+				if err := p.Send(produceCtx, batch...); err != nil {
+					if produceCtx.Err() != nil {
+						return
+					}
+					produceErrors.Add(int64(len(batch)))
+					continue
+				}
+				acked.Add(int64(len(batch)))
+				d := time.Since(now)
+				for range batch {
+					produceLat.add(d)
+				}
+			}
+		}(i, p)
+	}
+	pwg.Wait()
+	produceElapsed := time.Since(start)
+
+	sent := acked.Load()
+
+	// wait for consumers to catch up with what was acknowledged
+	if cfg.Consumers > 0 {
+		deadline := time.After(cfg.Drain)
+		tick := time.NewTicker(100 * time.Millisecond)
+	drain:
+		for total.Load() < sent {
+			select {
+			case <-tick.C:
+			case <-deadline:
+				break drain
+			case <-ctx.Done():
+				break drain
+			case err := <-consumeErr:
+				tick.Stop()
+				return loadTestReport{}, err
+			}
+		}
+		tick.Stop()
+	}
+	stopConsumers()
+	cwg.Wait()
+
+	r := loadTestReport{
+		Topic:          cfg.Topic,
+		Partitions:     partitions,
+		Producers:      cfg.Producers,
+		Consumers:      cfg.Consumers,
+		Group:          cfg.Group,
+		Size:           cfg.Size,
+		Acks:           cfg.Acks,
+		Compression:    cfg.Compression,
+		Partitioner:    cfg.Partitioner,
+		BatchSize:      cfg.BatchSize,
+		ProduceElapsed: produceElapsed,
+		Produced:       sent,
+		ProduceErrors:  produceErrors.Load(),
+		Consumed:       total.Load(),
+	}
+	if first, last := firstConsumed.Load(), lastConsumed.Load(); first != 0 {
+		r.ConsumeElapsed = time.Duration(last - first)
+	}
+	mib := float64(cfg.Size) / (1 << 20)
+	if s := r.ProduceElapsed.Seconds(); s > 0 {
+		r.ProduceRate = float64(r.Produced) / s
+		r.ProduceMBps = r.ProduceRate * mib
+	}
+	if s := r.ConsumeElapsed.Seconds(); s > 0 {
+		r.ConsumeRate = float64(r.Consumed) / s
+		r.ConsumeMBps = r.ConsumeRate * mib
+	}
+
+	var maxProduced, maxConsumed int64
+	for p := 0; p < partitions; p++ {
+		pl := partitionLoad{Partition: p, Produced: produced[p].Load(), Consumed: consumed[p].Load()}
+		maxProduced = max(maxProduced, pl.Produced)
+		maxConsumed = max(maxConsumed, pl.Consumed)
+		r.PerPartition = append(r.PerPartition, pl)
+	}
+	r.ProduceSkew = skew(maxProduced, r.Produced, partitions)
+	r.ConsumeSkew = skew(maxConsumed, r.Consumed, partitions)
+	r.ProduceLatency = produceLat.summary()
+	r.EndToEndLatency = endToEndLat.summary()
+	return r, nil
+}
+
+func skew(busiest, total int64, partitions int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(busiest) / (float64(total) / float64(partitions))
+}
+
+// startAtEnd makes a group without committed offsets start at the end of
+// the topic, so the consumers don't read through its history first.
+func startAtEnd(ctx context.Context, cfg loadTestConfig) error {
+	admin, err := kafka.NewAdmin(kafka.WithBrokers(cfg.Brokers...))
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	plan, err := admin.PlanOffsetReset(ctx, cfg.Group, cfg.Topic, kafka.ToLatest())
+	if err != nil {
+		return err
+	}
+	for _, c := range plan {
+		if c.Before >= 0 {
+			return nil
+		}
+	}
+	_, err = admin.ResetOffsets(ctx, cfg.Group, cfg.Topic, kafka.ToLatest())
+	return err
+}
+
+// ensurePartitions makes sure the topic exists with at least cfg.Partitions
+// partitions and returns how many it has.
+func ensurePartitions(ctx context.Context, cfg loadTestConfig) (int, error) {
+	admin, err := kafka.NewAdmin(kafka.WithBrokers(cfg.Brokers...))
+	if err != nil {
+		return 0, err
+	}
+	defer admin.Close()
+
+	if cfg.Partitions > 0 {
+		if err := admin.EnsureTopic(ctx, kafka.TopicSpec{
+			Name:              cfg.Topic,
+			Partitions:        cfg.Partitions,
+			ReplicationFactor: cfg.Replication,
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	info, err := admin.DescribeTopic(ctx, cfg.Topic)
+	if errors.Is(err, kafka.ErrUnknownTopic) {
+		return 0, fmt.Errorf("topic %v does not exist, pass -partitions to create it", cfg.Topic)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(info.Partitions), nil
+}
+
+func loadTestSent(value []byte, runID uint64) (time.Time, bool) {
+	if len(value) < loadTestPrefix || binary.BigEndian.Uint64(value) != runID {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(value[8:]))), true
+}
+
+type keyGen struct {
+	dist string
+	keys int
+	seq  int
+	rand *rand.Rand
+	zipf *rand.Zipf
+}
+
+func newKeyGen(dist string, keys int, seed int64) *keyGen {
+	g := &keyGen{
+		dist: dist,
+		keys: keys,
+		rand: rand.New(rand.NewSource(time.Now().UnixNano() + seed)),
+	}
+	if dist == "zipf" && keys > 1 {
+		g.zipf = rand.NewZipf(g.rand, 1.1, 1, uint64(keys-1))
+	}
+	return g
+}
+
+func (g *keyGen) next() []byte {
+	if g.keys <= 0 {
+		return nil
+	}
+	var k int
+	switch {
+	case g.dist == "sequential":
+		k = g.seq % g.keys
+		g.seq++
+	case g.zipf != nil:
+		k = int(g.zipf.Uint64())
+	default:
+		k = g.rand.Intn(g.keys)
+	}
+	return []byte(fmt.Sprintf("key-%v", k))
+}
+
+func (r loadTestReport) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r loadTestReport) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "topic\t%v (%v partitions)\n", r.Topic, r.Partitions)
+	fmt.Fprintf(tw, "producers/consumers\t%v/%v\n", r.Producers, r.Consumers)
+	fmt.Fprintf(tw, "group\t%v\n", r.Group)
+	fmt.Fprintf(tw, "message size\t%v B\n", r.Size)
+	fmt.Fprintf(tw, "acks/compression/batch\t%v/%v/%v\n", r.Acks, r.Compression, r.BatchSize)
+	fmt.Fprintf(tw, "partitioner\t%v\n", r.Partitioner)
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "\tmessages\terrors\telapsed\tmsg/s\tMiB/s")
+	fmt.Fprintf(tw, "produce\t%v\t%v\t%v\t%.0f\t%.2f\n", r.Produced, r.ProduceErrors, r.ProduceElapsed.Round(time.Millisecond), r.ProduceRate, r.ProduceMBps)
+	fmt.Fprintf(tw, "consume\t%v\t\t%v\t%.0f\t%.2f\n", r.Consumed, r.ConsumeElapsed.Round(time.Millisecond), r.ConsumeRate, r.ConsumeMBps)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	parts := append([]partitionLoad(nil), r.PerPartition...)
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Partition < parts[j].Partition })
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "partition\tproduced\tconsumed")
+	for _, p := range parts {
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", p.Partition, p.Produced, p.Consumed)
+	}
+	fmt.Fprintf(tw, "skew\t%.2f\t%.2f\n", r.ProduceSkew, r.ConsumeSkew)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	if err := r.ProduceLatency.write(w, "produce latency"); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	return r.EndToEndLatency.write(w, "end-to-end latency")
+}
@@ -4,148 +4,196 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"producer/kafka"
 	"sync"
-
-	kafka "github.com/segmentio/kafka-go"
+	"syscall"
 )
 
-var (
+const (
 	url   = "localhost:19092"
 	topic = "usernames"
-)
 
-type kafkaObj struct {
-	URL   string
-	Topic string
-}
+	usernameEvent = "username"
 
+	// queueWorkers is how many keys each queue consumer handles at once.
+	queueWorkers = 4
+	// consumeInFlight is how far demo consumers read ahead of their handler.
+	consumeInFlight = 64
+)
+
+// Message is a demo payload. Messages with the same key land on the same
+// partition.
 type Message struct {
-	Msg       string
-	Partition string
+	Key   string
+	Value string
 }
 
 func main() {
-	// KafkaQueue()
-	// KafkaPubSub()
-}
-
-func KafkaQueue() {
-	var wg sync.WaitGroup
-	consumerGrp := "grp1"
-	c := "c"
+	if len(os.Args) < 2 {
+		log.Fatal("usage: producer <queue|pubsub|loadtest|topic|lag|offsets|partitions|table|counts|mirror|export|import|tail|retry> [flags]")
+	}
 
-	k := kafkaObj{
-		URL:   url,
-		Topic: topic,
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var err error
+	switch cmd := os.Args[1]; cmd {
+	case "queue":
+		err = KafkaQueue(ctx)
+	case "pubsub":
+		err = KafkaPubSub(ctx)
+	case "loadtest":
+		err = loadTest(ctx, os.Args[2:])
+	case "topic":
+		err = topicCommand(ctx, os.Args[2:])
+	case "lag":
+		err = lagCommand(ctx, os.Args[2:])
+	case "offsets":
+		err = offsetsCommand(ctx, os.Args[2:])
+	case "partitions":
+		err = partitionsCommand(ctx, os.Args[2:])
+	case "table":
+		err = tableCommand(ctx, os.Args[2:])
+	case "counts":
+		err = countsCommand(ctx, os.Args[2:])
+	case "mirror":
+		err = mirrorCommand(ctx, os.Args[2:])
+	case "export":
+		err = exportCommand(ctx, os.Args[2:])
+	case "import":
+		err = importCommand(ctx, os.Args[2:])
+	case "tail":
+		err = tailCommand(ctx, os.Args[2:])
+	case "retry":
+		err = retryCommand(ctx, os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q", cmd)
 	}
-	if err := k.write(context.Background(),
-		[]Message{
-			{
-				Msg:       "message-1",
-				Partition: "p1",
-			},
-			{
-				Msg:       "message-2",
-				Partition: "p2",
-			},
-			{
-				Msg:       "message-3",
-				Partition: "p3",
-			},
-		}); err != nil {
+	if err != nil {
 		log.Fatal(err)
 	}
+}
 
-	for i := 0; i < 3; i++ {
-		wg.Add(1)
-		go k.consume(consumerGrp, fmt.Sprintf("%v-%v", c, i), &wg)
-	}
-	wg.Wait()
+// KafkaQueue shares the topic between consumers of one group, so every
+// message is handled once. Each consumer handles several keys of its
+// partitions in parallel.
+func KafkaQueue(ctx context.Context) error {
+	consumerGrp := "grp1"
 
+	groups := make([]string, 3)
+	for i := range groups {
+		groups[i] = consumerGrp
+	}
+	s := scenario{
+		name:     "queue",
+		messages: keyedMessages(5, "p1", "p2", "p3", "p4", "p5", "p6"),
+		groups:   groups,
+		opts:     []kafka.Option{kafka.WithKeyWorkers(queueWorkers)},
+	}
+	return s.run(ctx, os.Stdout)
 }
 
-func KafkaPubSub() {
-	var wg sync.WaitGroup
+// KafkaPubSub gives every consumer its own group, so each one sees every
+// message.
+func KafkaPubSub(ctx context.Context) error {
 	consumerGrp := "grp"
-	c := "c"
 
-	k := kafkaObj{
-		URL:   url,
-		Topic: topic,
-	}
-	if err := k.write(context.Background(),
-		[]Message{
-			{
-				Msg:       "message-1",
-				Partition: "p1",
-			},
-			{
-				Msg:       "message-2",
-				Partition: "p1",
-			},
-			{
-				Msg:       "message-3",
-				Partition: "p1",
-			},
-		}); err != nil {
-		log.Fatal(err)
+	groups := make([]string, 3)
+	for i := range groups {
+		groups[i] = fmt.Sprintf("%v-%v", consumerGrp, i)
 	}
-
-	for i := 0; i < 3; i++ {
-		wg.Add(1)
-		go k.consume(fmt.Sprintf("%v-%v", consumerGrp, i), fmt.Sprintf("%v-%v", c, i), &wg)
+	s := scenario{
+		name:     "pubsub",
+		messages: keyedMessages(5, "p1"),
+		groups:   groups,
 	}
-	wg.Wait()
-
+	return s.run(ctx, os.Stdout)
 }
 
-func (k *kafkaObj) write(ctx context.Context, msgs []Message) error {
-	w := kafka.NewWriter(
-		kafka.WriterConfig{
+// write sends msgs as envelopes with headers added to each.
+func write(ctx context.Context, msgs []Message, headers ...kafka.Header) error {
+	admin, err := kafka.NewAdmin(kafka.WithBrokers(url))
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	if err := admin.EnsureTopic(ctx, usernames); err != nil {
+		return err
+	}
 
-			Brokers:  []string{url},
-			Topic:    k.Topic,
-			Balancer: &kafka.Hash{},
-		},
+	p, err := kafka.NewProducer(
+		kafka.WithBrokers(url),
+		kafka.WithTopic(topic),
+		// place keys where our Java services expect them
+		kafka.WithPartitioner(kafka.PartitionerMurmur2),
 	)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
 
 	for _, m := range msgs {
-		fmt.Printf("writing %v to partition %v\n", m.Msg, m.Partition)
-		err := w.WriteMessages(ctx, kafka.Message{
-			Value: []byte(m.Msg),
-			Key:   []byte(m.Partition),
-		})
-		if err != nil {
+		fmt.Printf("writing %v with key %v\n", m.Value, m.Key)
+		env := kafka.Envelope{
+			Message: kafka.Message{
+				Key:   []byte(m.Key),
+				Value: []byte(m.Value),
+			},
+			EventType:     usernameEvent,
+			SchemaVersion: "1",
+			CorrelationID: kafka.NewCorrelationID(),
+			Trace:         kafka.NewTrace(),
+		}
+		m := env.ToMessage()
+		m.Headers = append(m.Headers, headers...)
+		if err := p.Send(ctx, m); err != nil {
 			return err
 		}
 	}
-
-	if err := w.Close(); err != nil {
-		log.Fatal("failed to close writer:", err)
-	}
-
 	return nil
 }
 
-func (k *kafkaObj) consume(consumerGrp, name string, wg *sync.WaitGroup) {
-	defer wg.Done()
-	r := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:  []string{k.URL},
-		GroupID:  consumerGrp,
-		Topic:    k.Topic,
-		MaxBytes: 10e6,
-	})
-
-	for {
-		m, err := r.ReadMessage(context.Background())
+// consume starts one consumer per entry in groups, named after name, and
+// passes what they read to handle until they all stop. It returns the first
+// error.
+func consume(ctx context.Context, groups []string, name string, handle func(group, consumer string, m kafka.Message), opts ...kafka.Option) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		cerr error
+	)
+	for i, grp := range groups {
+		c, err := kafka.NewConsumer(append([]kafka.Option{
+			kafka.WithBrokers(url),
+			kafka.WithTopic(topic),
+			kafka.WithGroup(grp),
+			kafka.WithMaxInFlight(consumeInFlight),
+		}, opts...)...)
 		if err != nil {
-			log.Fatal(err)
-			break
+			return err
 		}
-		fmt.Printf("message at consumer/topic/partition/offset %s/%v/%v/%v: %s = %s\n", name, m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
-	}
+		defer c.Close()
 
-	if err := r.Close(); err != nil {
-		log.Fatal("failed to close reader:", err)
+		wg.Add(1)
+		go func(grp, name string) {
+			defer wg.Done()
+			err := c.Run(ctx, func(ctx context.Context, m kafka.Message) error {
+				handle(grp, name, m)
+				return nil
+			})
+			if err != nil {
+				once.Do(func() {
+					cerr = fmt.Errorf("consumer %v: %w", name, err)
+					cancel()
+				})
+			}
+		}(grp, fmt.Sprintf("%v-%v", name, i))
 	}
+	wg.Wait()
+	return cerr
 }
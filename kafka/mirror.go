@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"producer/kafka"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// listFlag collects repeated string flags.
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ",") }
+func (l *listFlag) Set(s string) error { *l = append(*l, s); return nil }
+
+// mirrorCommand runs the "mirror" command.
+func mirrorCommand(ctx context.Context, args []string) error {
+	var (
+		cfg                      kafka.MirrorConfig
+		source, target           string
+		include, exclude, rename listFlag
+		workers                  int
+	)
+	fs := flag.NewFlagSet("mirror", flag.ExitOnError)
+	fs.StringVar(&source, "source", url, "comma separated source broker addresses")
+	fs.StringVar(&target, "target", url, "comma separated target broker addresses")
+	fs.StringVar(&cfg.Group, "group", "mirror", "prefix of the source consumer groups that checkpoint progress, one per topic")
+	fs.Var(&include, "include", "regexp of source topics to mirror, repeatable; all topics if none")
+	fs.Var(&exclude, "exclude", "regexp of source topics to skip, repeatable")
+	fs.Var(&rename, "rename", "rename rule from=to with $1 style groups, repeatable")
+	fs.BoolVar(&cfg.PreservePartitions, "preserve-partitions", false, "write records to their source partition number")
+	fs.BoolVar(&cfg.CreateTopics, "create", false, "create missing target topics")
+	fs.DurationVar(&cfg.Refresh, "refresh", time.Minute, "how often to look for new source topics, 0 to only look at start")
+	fs.IntVar(&workers, "workers", 1, "keys mirrored in parallel per topic; above 1 records of a partition may be reordered across keys")
+	fs.Parse(args)
+
+	for _, s := range include {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return fmt.Errorf("include %q: %w", s, err)
+		}
+		cfg.Include = append(cfg.Include, re)
+	}
+	for _, s := range exclude {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return fmt.Errorf("exclude %q: %w", s, err)
+		}
+		cfg.Exclude = append(cfg.Exclude, re)
+	}
+	for _, s := range rename {
+		r, err := kafka.ParseRenameRule(s)
+		if err != nil {
+			return err
+		}
+		cfg.Rename = append(cfg.Rename, r)
+	}
+
+	cfg.Source = []kafka.Option{
+		kafka.WithBrokers(strings.Split(source, ",")...),
+		kafka.WithKeyWorkers(workers),
+		kafka.WithCommitInterval(time.Second),
+		kafka.WithCommitBatch(100),
+	}
+	cfg.Target = []kafka.Option{
+		kafka.WithBrokers(strings.Split(target, ",")...),
+		// sends are synchronous per record, don't wait for batches to fill
+		kafka.WithLinger(time.Millisecond),
+	}
+
+	cfg.OnSkip = func(topic, reason string) {
+		fmt.Printf("skipping %v: %v\n", topic, reason)
+	}
+
+	m, err := kafka.NewMirror(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	topics, err := m.Topics(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range topics {
+		fmt.Printf("mirroring %v -> %v\n", t, m.TargetTopic(t))
+	}
+	return m.Run(ctx)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"producer/kafka"
+	"strings"
+	"text/tabwriter"
+)
+
+// offsetsCommand runs the "offsets" command. It only prints the planned
+// change unless -execute is given.
+func offsetsCommand(ctx context.Context, args []string) error {
+	var (
+		brokers string
+		group   string
+		topicN  string
+		to      string
+		execute bool
+		jsonOut bool
+	)
+	fs := flag.NewFlagSet("offsets", flag.ExitOnError)
+	fs.StringVar(&brokers, "brokers", url, "comma separated broker addresses")
+	fs.StringVar(&group, "group", "", "consumer group to reset")
+	fs.StringVar(&topicN, "topic", topic, "topic whose offsets are reset")
+	fs.StringVar(&to, "to", "", "earliest, latest, shift:N, time:RFC3339, offset:N or offset:P=N,P=N")
+	fs.BoolVar(&execute, "execute", false, "commit the new offsets instead of printing a dry run")
+	fs.BoolVar(&jsonOut, "json", false, "print JSON instead of a table")
+	fs.Parse(args)
+
+	if group == "" || to == "" {
+		return errors.New("usage: producer offsets -group GROUP -to TARGET [-topic TOPIC] [-execute]")
+	}
+	target, err := kafka.ParseResetTarget(to)
+	if err != nil {
+		return err
+	}
+
+	admin, err := kafka.NewAdmin(kafka.WithBrokers(strings.Split(brokers, ",")...))
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	var changes []kafka.OffsetChange
+	if execute {
+		changes, err = admin.ResetOffsets(ctx, group, topicN, target)
+	} else {
+		changes, err = admin.PlanOffsetReset(ctx, group, topicN, target)
+	}
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		return json.NewEncoder(os.Stdout).Encode(changes)
+	}
+	if !execute {
+		fmt.Println("dry run, pass -execute to apply")
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOPIC\tPARTITION\tBEFORE\tAFTER\tDELTA")
+	for _, c := range changes {
+		before, delta := "-", "-"
+		if c.Before >= 0 {
+			before = fmt.Sprint(c.Before)
+			delta = fmt.Sprintf("%+d", c.After-c.Before)
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", topicN, c.Partition, before, c.After, delta)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"producer/kafka"
+	"strings"
+	"text/tabwriter"
+)
+
+// partitionsCommand runs the "partitions" command, which shows where keys
+// land under each key-based partitioner.
+func partitionsCommand(ctx context.Context, args []string) error {
+	var (
+		brokers     string
+		topicN      string
+		count       int
+		partitioner string
+		keysFile    string
+	)
+	fs := flag.NewFlagSet("partitions", flag.ExitOnError)
+	fs.StringVar(&brokers, "brokers", url, "comma separated broker addresses, used when -count is 0")
+	fs.StringVar(&topicN, "topic", topic, "topic whose partition count is used when -count is 0")
+	fs.IntVar(&count, "count", 0, "partition count, 0 to read it from -topic")
+	fs.StringVar(&partitioner, "partitioner", "", "only show this partitioner")
+	fs.StringVar(&keysFile, "keys", "", "file with one key per line, - for stdin")
+	fs.Parse(args)
+
+	keys := fs.Args()
+	if keysFile != "" {
+		more, err := readKeys(keysFile)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, more...)
+	}
+	if len(keys) == 0 {
+		return errors.New("usage: producer partitions [-count N | -topic TOPIC] [-partitioner P] [-keys FILE] [key...]")
+	}
+
+	var strategies []kafka.Partitioner
+	if partitioner != "" {
+		p, err := kafka.ParsePartitioner(partitioner)
+		if err != nil {
+			return err
+		}
+		strategies = append(strategies, p)
+	} else {
+		for _, p := range kafka.Partitioners {
+			if p.ByKey() {
+				strategies = append(strategies, p)
+			}
+		}
+	}
+
+	if count == 0 {
+		admin, err := kafka.NewAdmin(kafka.WithBrokers(strings.Split(brokers, ",")...))
+		if err != nil {
+			return err
+		}
+		info, err := admin.DescribeTopic(ctx, topicN)
+		admin.Close()
+		if err != nil {
+			return err
+		}
+		count = len(info.Partitions)
+	}
+	fmt.Printf("%v partitions\n\n", count)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprint(tw, "KEY")
+	for _, p := range strategies {
+		fmt.Fprintf(tw, "\t%v", strings.ToUpper(string(p)))
+	}
+	fmt.Fprintln(tw)
+	for _, k := range keys {
+		fmt.Fprint(tw, k)
+		for _, p := range strategies {
+			n, err := p.PartitionFor([]byte(k), count)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(tw, "\t%v", n)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func readKeys(path string) ([]string, error) {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return nil, err
+		}
+		defer f.Close()
+	}
+
+	var keys []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if k := strings.TrimSpace(s.Text()); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys, s.Err()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"producer/kafka"
+	"strings"
+	"time"
+)
+
+// retryCommand runs the "retry" command, which consumes a topic through a
+// failure pipeline. Records whose value contains -fail fail every attempt,
+// so they move through the retry topics into the dead-letter topic.
+func retryCommand(ctx context.Context, args []string) error {
+	var (
+		brokers   string
+		topicN    string
+		group     string
+		fail      string
+		permanent bool
+		delays    string
+		policy    = kafka.DefaultRetryPolicy
+	)
+	fs := flag.NewFlagSet("retry", flag.ExitOnError)
+	fs.StringVar(&brokers, "brokers", url, "comma separated broker addresses")
+	fs.StringVar(&topicN, "topic", topic, "topic to consume")
+	fs.StringVar(&group, "group", topic+"-retry", "consumer group of the main topic, retry topics use <group>.retry.N")
+	fs.StringVar(&fail, "fail", "", "fail records whose value contains this")
+	fs.BoolVar(&permanent, "permanent", false, "fail matching records permanently, straight to the dead-letter topic")
+	fs.IntVar(&policy.Attempts, "attempts", policy.Attempts, "in-process attempts per stage")
+	fs.DurationVar(&policy.Backoff, "backoff", policy.Backoff, "pause before the second in-process attempt, doubling after")
+	fs.StringVar(&delays, "delays", "1s,30s,5m", "comma separated delay of each retry topic")
+	fs.Parse(args)
+
+	var err error
+	if policy.Delays, err = parseDurations(delays); err != nil {
+		return err
+	}
+
+	handle := func(ctx context.Context, m kafka.Message) error {
+		stage := "main"
+		if v, ok := m.Header(kafka.HeaderAttempts); ok {
+			stage = fmt.Sprintf("%v after %s attempts", m.Topic, v)
+		}
+		if fail != "" && strings.Contains(string(m.Value), fail) {
+			fmt.Printf("%v %v/%v %s failed (%v)\n", m.Topic, m.Partition, m.Offset, m.Key, stage)
+			err := fmt.Errorf("value contains %q", fail)
+			if permanent {
+				return kafka.Permanent(err)
+			}
+			return err
+		}
+		fmt.Printf("%v %v/%v %s handled (%v)\n", m.Topic, m.Partition, m.Offset, m.Key, stage)
+		return nil
+	}
+
+	f := kafka.NewFailurePipeline(topicN, handle, policy,
+		kafka.WithBrokers(strings.Split(brokers, ",")...),
+		kafka.WithGroup(group),
+	)
+	defer f.Close()
+
+	fmt.Printf("consuming %v, failures go to %v\n", topicN, strings.Join(f.Topics(), ", "))
+	return f.Run(ctx)
+}
+
+func parseDurations(s string) ([]time.Duration, error) {
+	var out []time.Duration
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		d, err := time.ParseDuration(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delay %q: %w", f, err)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"producer/kafka"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// scenarioTimeout bounds how long a scenario waits for its consumers to
+	// join and for every expected delivery.
+	scenarioTimeout = time.Minute
+	// scenarioSettle is how long a scenario keeps consuming after the last
+	// expected delivery, so that duplicates show up.
+	scenarioSettle = 2 * time.Second
+	// runHeader tags the messages of one scenario run; consumers ignore
+	// those of other runs.
+	runHeader = "x-scenario-run"
+)
+
+var errScenarioFailed = errors.New("scenario failed")
+
+// scenario publishes keyed messages, consumes them with one consumer per
+// entry in groups and checks what every group received. Consumers listed
+// with the same group share it.
+type scenario struct {
+	name     string
+	messages []Message
+	groups   []string
+	opts     []kafka.Option
+}
+
+// delivery is a scenario message handled by a consumer.
+type delivery struct {
+	group     string
+	consumer  string
+	index     int
+	partition int
+}
+
+// check is one line of a scenario report.
+type check struct {
+	name   string
+	ok     bool
+	detail string
+}
+
+// keyedMessages returns perKey messages for each key, interleaving the keys
+// so that ordering per key is observable.
+func keyedMessages(perKey int, keys ...string) []Message {
+	var msgs []Message
+	for range perKey {
+		for _, k := range keys {
+			msgs = append(msgs, Message{Key: k, Value: fmt.Sprintf("message-%v", len(msgs)+1)})
+		}
+	}
+	return msgs
+}
+
+// run runs the scenario and writes its report to w. It returns
+// errScenarioFailed when a check fails.
+func (s scenario) run(ctx context.Context, w io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, scenarioTimeout)
+	defer cancel()
+
+	// fresh groups keep offsets and members of earlier runs out of the way
+	runID := kafka.NewCorrelationID()
+	groups := make([]string, len(s.groups))
+	for i, g := range s.groups {
+		groups[i] = fmt.Sprintf("%v-%v", g, runID[:8])
+	}
+
+	admin, err := kafka.NewAdmin(kafka.WithBrokers(url))
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	if err := admin.EnsureTopic(ctx, usernames); err != nil {
+		return err
+	}
+	info, err := admin.DescribeTopic(ctx, topic)
+	if err != nil {
+		return err
+	}
+	for _, g := range distinct(groups) {
+		if _, err := admin.ResetOffsets(ctx, g, topic, kafka.ToLatest()); err != nil {
+			return err
+		}
+	}
+
+	index := map[string]int{}
+	for i, m := range s.messages {
+		index[m.Value] = i
+	}
+	var (
+		mu       sync.Mutex
+		got      []delivery
+		seen     = map[string]map[int]bool{}
+		complete = make(chan struct{})
+	)
+	for _, g := range groups {
+		seen[g] = map[int]bool{}
+	}
+	handle := func(group, consumer string, m kafka.Message) {
+		run, _ := m.Header(runHeader)
+		i, ok := index[string(m.Value)]
+		if string(run) != runID || !ok {
+			return
+		}
+		fmt.Fprintf(w, "%v read %s = %s at partition/offset %v/%v\n", consumer, m.Key, m.Value, m.Partition, m.Offset)
+
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, delivery{group: group, consumer: consumer, index: i, partition: m.Partition})
+		seen[group][i] = true
+		for _, g := range groups {
+			if len(seen[g]) < len(s.messages) {
+				return
+			}
+		}
+		select {
+		case <-complete:
+		default:
+			close(complete)
+		}
+	}
+
+	consumeCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	done := make(chan error, 1)
+	go func() { done <- consume(consumeCtx, groups, "c", handle, s.opts...) }()
+
+	// producing once every group is stable keeps rebalances from
+	// redelivering messages
+	err = waitForGroups(ctx, admin, groups)
+	if err == nil {
+		err = write(ctx, s.messages, kafka.Header{Key: runHeader, Value: []byte(runID)})
+	}
+	if err != nil {
+		stop()
+		return errors.Join(err, <-done)
+	}
+
+	select {
+	case <-complete:
+		select {
+		case <-time.After(scenarioSettle):
+		case <-ctx.Done():
+		}
+	case <-ctx.Done():
+	case err := <-done:
+		// consumers stopped on their own, report what they got
+		done <- err
+	}
+	stop()
+	if err := <-done; err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return s.report(w, runID, s.check(groups, len(info.Partitions), got))
+}
+
+// check asserts that every group got every message exactly once, that keys
+// landed on their partitions, that consumers sharing a group split the
+// partitions and that messages of a key were handled in order.
+func (s scenario) check(groups []string, partitions int, got []delivery) []check {
+	var checks []check
+	for _, g := range distinct(groups) {
+		counts := make([]int, len(s.messages))
+		for _, d := range got {
+			if d.group == g {
+				counts[d.index]++
+			}
+		}
+		var missing, dups []string
+		for i, n := range counts {
+			switch {
+			case n == 0:
+				missing = append(missing, s.messages[i].Value)
+			case n > 1:
+				dups = append(dups, s.messages[i].Value)
+			}
+		}
+		c := check{name: "delivery " + g, ok: len(missing) == 0 && len(dups) == 0}
+		if c.ok {
+			c.detail = fmt.Sprintf("%v messages, each once", len(s.messages))
+		} else {
+			c.detail = fmt.Sprintf("%v missing %v, %v duplicated %v", len(missing), missing, len(dups), dups)
+		}
+		checks = append(checks, c)
+	}
+
+	keyPartitions := map[string][]int{}
+	for _, d := range got {
+		k := s.messages[d.index].Key
+		if !slices.Contains(keyPartitions[k], d.partition) {
+			keyPartitions[k] = append(keyPartitions[k], d.partition)
+		}
+	}
+	c := check{name: "partitioning", ok: true}
+	var wrong []string
+	for _, k := range sortedKeys(keyPartitions) {
+		want, err := kafka.PartitionerMurmur2.PartitionFor([]byte(k), partitions)
+		if err != nil {
+			return append(checks, check{name: "partitioning", detail: err.Error()})
+		}
+		if ps := keyPartitions[k]; len(ps) != 1 || ps[0] != want {
+			wrong = append(wrong, fmt.Sprintf("%v on %v, want %v", k, joinInts(ps), want))
+		}
+	}
+	if len(wrong) > 0 {
+		c.ok, c.detail = false, strings.Join(wrong, "; ")
+	} else {
+		c.detail = fmt.Sprintf("%v keys on their murmur2 partition of %v", len(keyPartitions), partitions)
+	}
+	checks = append(checks, c)
+
+	for _, g := range distinct(groups) {
+		if count(groups, g) < 2 {
+			continue
+		}
+		readers := map[int][]string{}
+		for _, d := range got {
+			if d.group == g && !slices.Contains(readers[d.partition], d.consumer) {
+				readers[d.partition] = append(readers[d.partition], d.consumer)
+			}
+		}
+		c := check{name: "sharing " + g, ok: true}
+		var parts []string
+		for _, p := range sortedKeys(readers) {
+			slices.Sort(readers[p])
+			parts = append(parts, fmt.Sprintf("%v: %v", p, strings.Join(readers[p], ",")))
+			if len(readers[p]) > 1 {
+				c.ok = false
+			}
+		}
+		c.detail = "partition readers " + strings.Join(parts, "; ")
+		checks = append(checks, c)
+	}
+
+	for _, g := range distinct(groups) {
+		last := map[string]int{}
+		handled := map[int]bool{}
+		c := check{name: "ordering " + g, ok: true, detail: "messages of each key in produce order"}
+		for _, d := range got {
+			if d.group != g || handled[d.index] {
+				continue
+			}
+			handled[d.index] = true
+			k := s.messages[d.index].Key
+			if prev, ok := last[k]; ok && prev > d.index {
+				c.ok = false
+				c.detail = fmt.Sprintf("key %v: %v after %v", k, s.messages[d.index].Value, s.messages[prev].Value)
+				break
+			}
+			last[k] = d.index
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+func (s scenario) report(w io.Writer, runID string, checks []check) error {
+	fmt.Fprintf(w, "\nscenario %v, run %v\n", s.name, runID)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	failed := 0
+	for _, c := range checks {
+		status := "PASS"
+		if !c.ok {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", status, c.name, c.detail)
+	}
+	tw.Flush()
+
+	if failed > 0 {
+		fmt.Fprintf(w, "FAIL: %v of %v checks failed\n", failed, len(checks))
+		return fmt.Errorf("%w: %v", errScenarioFailed, s.name)
+	}
+	fmt.Fprintf(w, "PASS: %v checks\n", len(checks))
+	return nil
+}
+
+// waitForGroups waits until every group is stable with as many members as
+// it has entries in groups.
+func waitForGroups(ctx context.Context, admin *kafka.Admin, groups []string) error {
+	for {
+		ready := true
+		for _, g := range distinct(groups) {
+			info, err := admin.DescribeGroup(ctx, g)
+			if err != nil {
+				return err
+			}
+			if info.State != "Stable" || len(info.Members) != count(groups, g) {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			return nil
+		}
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for consumers to join: %w", ctx.Err())
+		}
+	}
+}
+
+// distinct returns the values of s without repeats, in order.
+func distinct(s []string) []string {
+	var out []string
+	for _, v := range s {
+		if !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func count(s []string, v string) int {
+	n := 0
+	for _, x := range s {
+		if x == v {
+			n++
+		}
+	}
+	return n
+}
+
+func sortedKeys[K string | int, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,286 @@
+// Package schema encodes Kafka payloads in the Confluent wire format, with
+// schemas kept in a Confluent-compatible schema registry.
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type Type string
+
+const (
+	Avro     Type = "AVRO"
+	Protobuf Type = "PROTOBUF"
+	JSON     Type = "JSON"
+)
+
+// Compatibility is a registry compatibility level.
+type Compatibility string
+
+const (
+	CompatibilityNone               Compatibility = "NONE"
+	CompatibilityBackward           Compatibility = "BACKWARD"
+	CompatibilityBackwardTransitive Compatibility = "BACKWARD_TRANSITIVE"
+	CompatibilityForward            Compatibility = "FORWARD"
+	CompatibilityForwardTransitive  Compatibility = "FORWARD_TRANSITIVE"
+	CompatibilityFull               Compatibility = "FULL"
+	CompatibilityFullTransitive     Compatibility = "FULL_TRANSITIVE"
+)
+
+// Schema is a registered schema. ID, Subject and Version are only set on
+// schemas returned by the registry.
+type Schema struct {
+	ID      int    `json:"id,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Type    Type   `json:"schemaType,omitempty"`
+	Text    string `json:"schema"`
+}
+
+// ValueSubject and KeySubject name subjects after the topic, like the
+// Confluent serializers' default TopicNameStrategy.
+func ValueSubject(topic string) string { return topic + "-value" }
+func KeySubject(topic string) string   { return topic + "-key" }
+
+// Registry error codes used by Confluent-compatible registries.
+const (
+	CodeSubjectNotFound = 40401
+	CodeVersionNotFound = 40402
+	CodeSchemaNotFound  = 40403
+	CodeIncompatible    = 409
+	CodeInvalidSchema   = 42201
+)
+
+// Error is returned for non 2xx responses.
+type Error struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("schema registry returned %v (%v): %v", e.StatusCode, e.Code, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the registry.
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// IsIncompatible reports whether the registry rejected a schema because it
+// breaks the subject's compatibility level.
+func IsIncompatible(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusConflict
+}
+
+// Client talks to the registry and caches what never changes: schemas by
+// ID and the IDs of schemas it registered.
+type Client struct {
+	BaseURL    string
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+
+	mu         sync.Mutex
+	byID       map[int]Schema
+	registered map[string]int
+}
+
+// NewClient returns a client for the registry at baseURL, e.g.
+// http://localhost:8081. Empty credentials disable basic auth.
+func NewClient(baseURL, username, password string) *Client {
+	return &Client{
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		Username: username,
+		Password: password,
+	}
+}
+
+// Register adds s to subject, or finds it if it is already there, and
+// returns its ID. The registry refuses schemas that break the subject's
+// compatibility level, see IsIncompatible.
+func (c *Client) Register(ctx context.Context, subject string, s Schema) (int, error) {
+	key := subject + "\x00" + string(s.Type) + "\x00" + s.Text
+	c.mu.Lock()
+	id, ok := c.registered[key]
+	c.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	var out struct {
+		ID int `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", request(s), &out); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	c.initCache()
+	c.registered[key] = out.ID
+	c.byID[out.ID] = Schema{ID: out.ID, Type: typeOrAvro(s.Type), Text: s.Text}
+	c.mu.Unlock()
+	return out.ID, nil
+}
+
+// SchemaByID returns the schema a payload was written with.
+func (c *Client) SchemaByID(ctx context.Context, id int) (Schema, error) {
+	c.mu.Lock()
+	s, ok := c.byID[id]
+	c.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &s); err != nil {
+		return Schema{}, err
+	}
+	s.ID = id
+	s.Type = typeOrAvro(s.Type)
+
+	c.mu.Lock()
+	c.initCache()
+	c.byID[id] = s
+	c.mu.Unlock()
+	return s, nil
+}
+
+// Latest returns the newest version registered under subject. It is not
+// cached since new versions may appear at any time.
+func (c *Client) Latest(ctx context.Context, subject string) (Schema, error) {
+	return c.Version(ctx, subject, "latest")
+}
+
+// Version returns a version of subject, a number or "latest".
+func (c *Client) Version(ctx context.Context, subject, version string) (Schema, error) {
+	var s Schema
+	if err := c.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/"+version, nil, &s); err != nil {
+		return Schema{}, err
+	}
+	s.Type = typeOrAvro(s.Type)
+	return s, nil
+}
+
+func (c *Client) Subjects(ctx context.Context) ([]string, error) {
+	var out []string
+	err := c.do(ctx, http.MethodGet, "/subjects", nil, &out)
+	return out, err
+}
+
+// Compatible checks s against the latest version of subject under the
+// subject's compatibility level, without registering it. A subject with no
+// versions accepts anything.
+func (c *Client) Compatible(ctx context.Context, subject string, s Schema) (bool, error) {
+	var out struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	err := c.do(ctx, http.MethodPost, "/compatibility/subjects/"+url.PathEscape(subject)+"/versions/latest", request(s), &out)
+	if IsNotFound(err) {
+		return true, nil
+	}
+	return out.IsCompatible, err
+}
+
+// Compatibility returns the level of subject, or the global level when
+// subject is empty.
+func (c *Client) Compatibility(ctx context.Context, subject string) (Compatibility, error) {
+	var out struct {
+		Level Compatibility `json:"compatibilityLevel"`
+	}
+	err := c.do(ctx, http.MethodGet, configPath(subject), nil, &out)
+	return out.Level, err
+}
+
+// SetCompatibility sets the level of subject, or the global level when
+// subject is empty.
+func (c *Client) SetCompatibility(ctx context.Context, subject string, level Compatibility) error {
+	body := map[string]Compatibility{"compatibility": level}
+	return c.do(ctx, http.MethodPut, configPath(subject), body, nil)
+}
+
+// initCache allows clients built without NewClient. Callers hold mu.
+func (c *Client) initCache() {
+	if c.byID == nil {
+		c.byID = map[int]Schema{}
+		c.registered = map[string]int{}
+	}
+}
+
+func configPath(subject string) string {
+	if subject == "" {
+		return "/config"
+	}
+	return "/config/" + url.PathEscape(subject)
+}
+
+// request is the body of register and compatibility calls; the registry
+// expects schemaType to be left out for Avro.
+func request(s Schema) any {
+	body := map[string]string{"schema": s.Text}
+	if s.Type != "" && s.Type != Avro {
+		body["schemaType"] = string(s.Type)
+	}
+	return body
+}
+
+func typeOrAvro(t Type) Type {
+	if t == "" {
+		return Avro
+	}
+	return t
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		raw, _ := io.ReadAll(resp.Body)
+		e := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(raw, e) != nil || e.Message == "" {
+			e.Message = strings.TrimSpace(string(raw))
+		}
+		return e
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,95 @@
+package schema_test
+
+import (
+	"context"
+	"producer/schema"
+	"producer/schema/registrytest"
+	"testing"
+)
+
+const userV1 = `{"type":"record","name":"User","fields":[{"name":"name","type":"string"}]}`
+
+func TestRegisterAndFetchAreCached(t *testing.T) {
+	srv := registrytest.NewServer()
+	defer srv.Close()
+	c := schema.NewClient(srv.URL, "", "")
+	ctx := context.Background()
+
+	id, err := c.Register(ctx, "users-value", schema.Schema{Type: schema.Avro, Text: userV1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := c.Register(ctx, "users-value", schema.Schema{Type: schema.Avro, Text: userV1})
+	if err != nil || again != id {
+		t.Fatalf("second register = %v, %v, want %v", again, err, id)
+	}
+	if n := srv.Requests(); n != 1 {
+		t.Fatalf("registering twice made %v requests, want 1", n)
+	}
+
+	s, err := c.SchemaByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Text != userV1 || s.Type != schema.Avro {
+		t.Fatalf("schema by id = %+v", s)
+	}
+	if n := srv.Requests(); n != 1 {
+		t.Fatalf("fetching a registered schema made %v requests, want none", n-1)
+	}
+
+	// a fresh client has to ask once, then serves from its cache
+	fresh := schema.NewClient(srv.URL, "", "")
+	for range 2 {
+		if _, err := fresh.SchemaByID(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := srv.Requests(); n != 2 {
+		t.Fatalf("fetching twice with a fresh client made %v requests, want 1", n-1)
+	}
+
+	latest, err := c.Latest(ctx, "users-value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.ID != id || latest.Version != 1 {
+		t.Fatalf("latest = %+v", latest)
+	}
+
+	if _, err := c.SchemaByID(ctx, 999); !schema.IsNotFound(err) {
+		t.Fatalf("unknown id: err = %v, want not found", err)
+	}
+}
+
+func TestIncompatibleSchemaIsRejected(t *testing.T) {
+	srv := registrytest.NewServer()
+	defer srv.Close()
+	c := schema.NewClient(srv.URL, "", "")
+	ctx := context.Background()
+
+	if _, err := c.Register(ctx, "users-value", schema.Schema{Type: schema.Avro, Text: userV1}); err != nil {
+		t.Fatal(err)
+	}
+	// a new field without a default can't be read from old records
+	breaking := schema.Schema{Type: schema.Avro, Text: `{"type":"record","name":"User","fields":[
+		{"name":"name","type":"string"},{"name":"age","type":"int"}]}`}
+
+	ok, err := c.Compatible(ctx, "users-value", breaking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("breaking schema reported compatible")
+	}
+	_, err = c.Register(ctx, "users-value", breaking)
+	if !schema.IsIncompatible(err) {
+		t.Fatalf("register breaking schema: err = %v, want incompatible", err)
+	}
+
+	compatible := schema.Schema{Type: schema.Avro, Text: `{"type":"record","name":"User","fields":[
+		{"name":"name","type":"string"},{"name":"age","type":"int","default":0}]}`}
+	if _, err := c.Register(ctx, "users-value", compatible); err != nil {
+		t.Fatalf("register compatible schema: %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+// Package registrytest provides an in-process Confluent-compatible schema
+// registry for exercising serializers without a real registry.
+package registrytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"producer/schema"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+)
+
+// Server is an httptest server implementing the parts of the registry API
+// that schema.Client uses. Compatibility is only enforced between Avro
+// schemas; JSON and Protobuf schemas are always accepted.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	schemas  []schema.Schema // by ID - 1
+	subjects map[string][]int
+	levels   map[string]schema.Compatibility
+	requests int
+}
+
+// NewServer starts an empty registry whose global level is BACKWARD, the
+// Confluent default.
+func NewServer() *Server {
+	s := &Server{
+		subjects: map[string][]int{},
+		levels:   map[string]schema.Compatibility{"": schema.CompatibilityBackward},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /subjects", s.listSubjects)
+	mux.HandleFunc("POST /subjects/{subject}/versions", s.register)
+	mux.HandleFunc("GET /subjects/{subject}/versions/{version}", s.version)
+	mux.HandleFunc("GET /schemas/ids/{id}", s.byID)
+	mux.HandleFunc("POST /compatibility/subjects/{subject}/versions/{version}", s.compatible)
+	mux.HandleFunc("GET /config", s.getConfig)
+	mux.HandleFunc("GET /config/{subject}", s.getConfig)
+	mux.HandleFunc("PUT /config", s.putConfig)
+	mux.HandleFunc("PUT /config/{subject}", s.putConfig)
+	s.Server = httptest.NewServer(s.count(mux))
+	return s
+}
+
+// Requests returns how many API calls were served, to check client caching.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) count(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+		next.ServeHTTP(w, r)
+	})
+}
+
+type schemaRequest struct {
+	Schema     string      `json:"schema"`
+	SchemaType schema.Type `json:"schemaType"`
+}
+
+func (s *Server) decode(w http.ResponseWriter, r *http.Request) (schema.Schema, bool) {
+	var req schemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fail(w, http.StatusUnprocessableEntity, schema.CodeInvalidSchema, err.Error())
+		return schema.Schema{}, false
+	}
+	sc := schema.Schema{Type: req.SchemaType, Text: req.Schema}
+	if sc.Type == "" {
+		sc.Type = schema.Avro
+	}
+	if err := validate(sc); err != nil {
+		fail(w, http.StatusUnprocessableEntity, schema.CodeInvalidSchema, err.Error())
+		return schema.Schema{}, false
+	}
+	return sc, true
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	sc, ok := s.decode(w, r)
+	if !ok {
+		return
+	}
+	subject := r.PathValue("subject")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range s.subjects[subject] {
+		if same(s.schemas[id-1], sc) {
+			reply(w, map[string]int{"id": id})
+			return
+		}
+	}
+	if !s.compatibleLocked(subject, sc, false) {
+		fail(w, http.StatusConflict, schema.CodeIncompatible, "Schema being registered is incompatible with an earlier schema")
+		return
+	}
+
+	id := 0
+	for i, existing := range s.schemas {
+		if same(existing, sc) {
+			id = i + 1
+		}
+	}
+	if id == 0 {
+		s.schemas = append(s.schemas, sc)
+		id = len(s.schemas)
+	}
+	s.subjects[subject] = append(s.subjects[subject], id)
+	reply(w, map[string]int{"id": id})
+}
+
+func (s *Server) version(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subject := r.PathValue("subject")
+	versions, ok := s.subjects[subject]
+	if !ok {
+		fail(w, http.StatusNotFound, schema.CodeSubjectNotFound, "Subject not found")
+		return
+	}
+	n := len(versions)
+	if v := r.PathValue("version"); v != "latest" {
+		var err error
+		if n, err = strconv.Atoi(v); err != nil || n < 1 || n > len(versions) {
+			fail(w, http.StatusNotFound, schema.CodeVersionNotFound, "Version not found")
+			return
+		}
+	}
+	id := versions[n-1]
+	out := s.schemas[id-1]
+	out.ID, out.Subject, out.Version = id, subject, n
+	reply(w, wire(out))
+}
+
+func (s *Server) byID(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 || id > len(s.schemas) {
+		fail(w, http.StatusNotFound, schema.CodeSchemaNotFound, "Schema not found")
+		return
+	}
+	reply(w, wire(schema.Schema{Type: s.schemas[id-1].Type, Text: s.schemas[id-1].Text}))
+}
+
+func (s *Server) listSubjects(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := []string{}
+	for subject := range s.subjects {
+		out = append(out, subject)
+	}
+	sort.Strings(out)
+	reply(w, out)
+}
+
+func (s *Server) compatible(w http.ResponseWriter, r *http.Request) {
+	sc, ok := s.decode(w, r)
+	if !ok {
+		return
+	}
+	subject := r.PathValue("subject")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subjects[subject]; !ok {
+		fail(w, http.StatusNotFound, schema.CodeSubjectNotFound, "Subject not found")
+		return
+	}
+	reply(w, map[string]bool{"is_compatible": s.compatibleLocked(subject, sc, r.PathValue("version") == "latest")})
+}
+
+func (s *Server) getConfig(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	level, ok := s.levels[r.PathValue("subject")]
+	if !ok {
+		fail(w, http.StatusNotFound, schema.CodeSubjectNotFound, "Subject does not have subject-level compatibility configured")
+		return
+	}
+	reply(w, map[string]schema.Compatibility{"compatibilityLevel": level})
+}
+
+func (s *Server) putConfig(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Compatibility schema.Compatibility `json:"compatibility"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Compatibility == "" {
+		fail(w, http.StatusUnprocessableEntity, 42203, "Invalid compatibility level")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.levels[r.PathValue("subject")] = req.Compatibility
+	reply(w, req)
+}
+
+// compatibleLocked checks sc against the subject's versions under its
+// level. onlyLatest limits transitive levels to the latest version, as the
+// compatibility endpoint does when asked about "latest".
+func (s *Server) compatibleLocked(subject string, sc schema.Schema, onlyLatest bool) bool {
+	level, ok := s.levels[subject]
+	if !ok {
+		level = s.levels[""]
+	}
+	ids := s.subjects[subject]
+	if len(ids) == 0 || level == schema.CompatibilityNone {
+		return true
+	}
+
+	switch level {
+	case schema.CompatibilityBackward, schema.CompatibilityForward, schema.CompatibilityFull:
+		onlyLatest = true
+	}
+	if onlyLatest {
+		ids = ids[len(ids)-1:]
+	}
+
+	for _, id := range ids {
+		old := s.schemas[id-1]
+		var ok bool
+		switch level {
+		case schema.CompatibilityBackward, schema.CompatibilityBackwardTransitive:
+			ok = readable(sc, old)
+		case schema.CompatibilityForward, schema.CompatibilityForwardTransitive:
+			ok = readable(old, sc)
+		default:
+			ok = readable(sc, old) && readable(old, sc)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// readable reports whether data written with writer can be read with
+// reader.
+func readable(reader, writer schema.Schema) bool {
+	if reader.Type != writer.Type {
+		return false
+	}
+	if reader.Type != schema.Avro {
+		return true
+	}
+	r, err := parseAvro(reader.Text)
+	if err != nil {
+		return false
+	}
+	w, err := parseAvro(writer.Text)
+	if err != nil {
+		return false
+	}
+	return avro.NewSchemaCompatibility().Compatible(r, w) == nil
+}
+
+func validate(sc schema.Schema) error {
+	switch sc.Type {
+	case schema.Avro:
+		_, err := parseAvro(sc.Text)
+		return err
+	case schema.JSON:
+		if !json.Valid([]byte(sc.Text)) {
+			return fmt.Errorf("invalid JSON schema")
+		}
+	case schema.Protobuf:
+		if sc.Text == "" {
+			return fmt.Errorf("empty protobuf schema")
+		}
+	default:
+		return fmt.Errorf("unknown schema type %v", sc.Type)
+	}
+	return nil
+}
+
+func parseAvro(text string) (avro.Schema, error) {
+	return avro.ParseWithCache(text, "", &avro.SchemaCache{})
+}
+
+func same(a, b schema.Schema) bool {
+	return a.Type == b.Type && a.Text == b.Text
+}
+
+// wire leaves schemaType out for Avro like the real registry.
+func wire(sc schema.Schema) schema.Schema {
+	if sc.Type == schema.Avro {
+		sc.Type = ""
+	}
+	return sc
+}
+
+func reply(w http.ResponseWriter, v any) {
+	json.NewEncoder(w).Encode(v)
+}
+
+func fail(w http.ResponseWriter, status, code int, msg string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(schema.Error{Code: code, Message: msg})
+}
@@ -0,0 +1,313 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"producer/kafka"
+	"strings"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// magicByte starts every payload in the Confluent wire format, followed by
+// the schema ID as a big endian uint32.
+const magicByte = 0
+
+var ErrNotWireFormat = errors.New("schema: payload is not in the Confluent wire format")
+
+// codec turns values into payload bytes and back for one schema type.
+type codec interface {
+	encode(v any) ([]byte, error)
+	// decode reads data written with the schema writer into v.
+	decode(writer Schema, data []byte, v any) error
+}
+
+// Serde writes values with one schema of a subject and reads values written
+// with any schema the registry knows. The schema is registered on first use.
+type Serde struct {
+	client  *Client
+	subject string
+	schema  Schema
+	codec   codec
+
+	mu sync.Mutex
+	id int
+}
+
+// NewAvroSerde encodes Go values with an Avro schema; struct fields map to
+// record fields through avro tags.
+func NewAvroSerde(c *Client, subject, text string) (*Serde, error) {
+	s, err := parseAvro(text)
+	if err != nil {
+		return nil, fmt.Errorf("schema: parsing avro schema: %w", err)
+	}
+	return newSerde(c, subject, Schema{Type: Avro, Text: text}, &avroCodec{schema: s, writers: map[string]avro.Schema{}}), nil
+}
+
+// NewJSONSerde encodes Go values as JSON described by a JSON Schema. Values
+// are validated against it before they are written, and payloads against
+// the schema they were written with before they are read.
+func NewJSONSerde(c *Client, subject, text string) (*Serde, error) {
+	s, err := compileJSONSchema(text)
+	if err != nil {
+		return nil, err
+	}
+	return newSerde(c, subject, Schema{Type: JSON, Text: text}, &jsonCodec{schema: s, writers: map[string]*jsonschema.Schema{}}), nil
+}
+
+// NewProtobufSerde encodes proto.Message values; text is the .proto source
+// registered for the subject.
+func NewProtobufSerde(c *Client, subject, text string) (*Serde, error) {
+	return newSerde(c, subject, Schema{Type: Protobuf, Text: text}, protobufCodec{}), nil
+}
+
+func newSerde(c *Client, subject string, s Schema, codec codec) *Serde {
+	return &Serde{client: c, subject: subject, schema: s, codec: codec}
+}
+
+func (s *Serde) Subject() string {
+	return s.subject
+}
+
+// ID registers the serde's schema if needed and returns its ID.
+func (s *Serde) ID(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.id != 0 {
+		return s.id, nil
+	}
+	id, err := s.client.Register(ctx, s.subject, s.schema)
+	if err != nil {
+		return 0, err
+	}
+	s.id = id
+	return id, nil
+}
+
+// Encode returns v in the wire format.
+func (s *Serde) Encode(ctx context.Context, v any) ([]byte, error) {
+	id, err := s.ID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := s.codec.encode(v)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 5, 5+len(payload))
+	out[0] = magicByte
+	binary.BigEndian.PutUint32(out[1:], uint32(id))
+	return append(out, payload...), nil
+}
+
+// Decode reads a wire format payload into v, using the schema it was
+// written with.
+func (s *Serde) Decode(ctx context.Context, data []byte, v any) error {
+	id, payload, err := Split(data)
+	if err != nil {
+		return err
+	}
+	writer, err := s.client.SchemaByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if writer.Type != s.schema.Type {
+		return fmt.Errorf("schema: payload was written with a %v schema, not %v", writer.Type, s.schema.Type)
+	}
+	return s.codec.decode(writer, payload, v)
+}
+
+// Message encodes v as the value of a Kafka message.
+func (s *Serde) Message(ctx context.Context, key []byte, v any) (kafka.Message, error) {
+	value, err := s.Encode(ctx, v)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	return kafka.Message{Key: key, Value: value}, nil
+}
+
+// Split returns the schema ID and the payload of wire format data.
+func Split(data []byte) (int, []byte, error) {
+	if len(data) < 5 || data[0] != magicByte {
+		return 0, nil, ErrNotWireFormat
+	}
+	return int(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+}
+
+type avroCodec struct {
+	schema avro.Schema
+
+	mu      sync.Mutex
+	writers map[string]avro.Schema
+}
+
+func (c *avroCodec) encode(v any) ([]byte, error) {
+	return avro.Marshal(c.schema, v)
+}
+
+// decode resolves the writer schema against ours, so payloads written
+// with older or newer compatible schemas read into the same type.
+func (c *avroCodec) decode(writer Schema, data []byte, v any) error {
+	c.mu.Lock()
+	resolved, ok := c.writers[writer.Text]
+	c.mu.Unlock()
+
+	if !ok {
+		ws, err := parseAvro(writer.Text)
+		if err != nil {
+			return fmt.Errorf("schema: parsing writer schema %v: %w", writer.ID, err)
+		}
+		resolved = c.schema
+		if ws.Fingerprint() != c.schema.Fingerprint() {
+			resolved, err = avro.NewSchemaCompatibility().Resolve(c.schema, ws)
+			if err != nil {
+				return fmt.Errorf("schema: writer schema %v is not readable: %w", writer.ID, err)
+			}
+		}
+		c.mu.Lock()
+		c.writers[writer.Text] = resolved
+		c.mu.Unlock()
+	}
+	return avro.Unmarshal(resolved, data, v)
+}
+
+// parseAvro parses with a cache of its own, so different versions of a
+// record do not clash in the package wide cache of named types.
+func parseAvro(text string) (avro.Schema, error) {
+	return avro.ParseWithCache(text, "", &avro.SchemaCache{})
+}
+
+type jsonCodec struct {
+	schema *jsonschema.Schema
+
+	mu      sync.Mutex
+	writers map[string]*jsonschema.Schema
+}
+
+func (c *jsonCodec) encode(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateJSON(c.schema, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *jsonCodec) decode(writer Schema, data []byte, v any) error {
+	c.mu.Lock()
+	ws, ok := c.writers[writer.Text]
+	c.mu.Unlock()
+
+	if !ok {
+		var err error
+		if ws, err = compileJSONSchema(writer.Text); err != nil {
+			return fmt.Errorf("schema: writer schema %v: %w", writer.ID, err)
+		}
+		c.mu.Lock()
+		c.writers[writer.Text] = ws
+		c.mu.Unlock()
+	}
+	if err := validateJSON(ws, data); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ErrInvalidJSON is returned for JSON values that don't match their schema.
+var ErrInvalidJSON = errors.New("schema: JSON value does not match its schema")
+
+func compileJSONSchema(text string) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(text))
+	if err != nil {
+		return nil, fmt.Errorf("schema: JSON schema is not valid JSON: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource("schema.json", doc); err != nil {
+		return nil, fmt.Errorf("schema: adding JSON schema: %w", err)
+	}
+	s, err := c.Compile("schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("schema: compiling JSON schema: %w", err)
+	}
+	return s, nil
+}
+
+func validateJSON(s *jsonschema.Schema, data []byte) error {
+	inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if err := s.Validate(inst); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	return nil
+}
+
+// protobufCodec prefixes payloads with the message indexes that locate the
+// message type within the registered .proto file.
+type protobufCodec struct{}
+
+func (protobufCodec) encode(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("schema: %T is not a proto.Message", v)
+	}
+	payload, err := proto.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return append(messageIndexes(m.ProtoReflect().Descriptor()), payload...), nil
+}
+
+func (protobufCodec) decode(_ Schema, data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("schema: %T is not a proto.Message", v)
+	}
+	n, size := binary.Varint(data)
+	if size <= 0 || n < 0 {
+		return ErrNotWireFormat
+	}
+	data = data[size:]
+	for i := int64(0); i < n; i++ {
+		if _, size = binary.Varint(data); size <= 0 {
+			return ErrNotWireFormat
+		}
+		data = data[size:]
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// messageIndexes encodes the path from the file to the message type as
+// zigzag varints, count first. The common case of the first message in the
+// file is written as a single zero.
+func messageIndexes(d protoreflect.MessageDescriptor) []byte {
+	var path []int
+	for {
+		path = append([]int{d.Index()}, path...)
+		parent, ok := d.Parent().(protoreflect.MessageDescriptor)
+		if !ok {
+			break
+		}
+		d = parent
+	}
+	if len(path) == 1 && path[0] == 0 {
+		return []byte{0}
+	}
+	out := binary.AppendVarint(nil, int64(len(path)))
+	for _, i := range path {
+		out = binary.AppendVarint(out, int64(i))
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package schema_test
+
+import (
+	"context"
+	"errors"
+	"producer/schema"
+	"producer/schema/registrytest"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type user struct {
+	Name string `avro:"name" json:"name"`
+	Age  int    `avro:"age" json:"age"`
+}
+
+func newClient(t *testing.T) *schema.Client {
+	t.Helper()
+	srv := registrytest.NewServer()
+	t.Cleanup(srv.Close)
+	return schema.NewClient(srv.URL, "", "")
+}
+
+func TestAvroRoundTrip(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+
+	v1, err := schema.NewAvroSerde(c, "users-value", userV1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := v1.Encode(ctx, user{Name: "ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _, err := schema.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := v1.ID(ctx); id != want {
+		t.Fatalf("payload schema id = %v, want %v", id, want)
+	}
+
+	// a newer reader fills the added field from its default
+	v2, err := schema.NewAvroSerde(c, "users-value", `{"type":"record","name":"User","fields":[
+		{"name":"name","type":"string"},{"name":"age","type":"int","default":42}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got user
+	if err := v2.Decode(ctx, data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != (user{Name: "ada", Age: 42}) {
+		t.Fatalf("decoded %+v", got)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+
+	s, err := schema.NewJSONSerde(c, "users-value", `{
+		"type": "object",
+		"properties": {"name": {"type": "string"}, "age": {"type": "integer", "minimum": 0}},
+		"required": ["name"]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.Encode(ctx, user{Name: "ada", Age: 36})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got user
+	if err := s.Decode(ctx, data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != (user{Name: "ada", Age: 36}) {
+		t.Fatalf("decoded %+v", got)
+	}
+
+	if _, err := s.Encode(ctx, user{Name: "ada", Age: -1}); !errors.Is(err, schema.ErrInvalidJSON) {
+		t.Fatalf("encoding an invalid value: err = %v, want ErrInvalidJSON", err)
+	}
+	// a payload that bypassed the serde is checked when read
+	invalid := append(append([]byte{}, data[:5]...), `{"age": 3}`...)
+	if err := s.Decode(ctx, invalid, &got); !errors.Is(err, schema.ErrInvalidJSON) {
+		t.Fatalf("decoding an invalid payload: err = %v, want ErrInvalidJSON", err)
+	}
+}
+
+func TestProtobufRoundTrip(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+
+	// StringValue is not the first message of wrappers.proto, so the
+	// payload carries a message index
+	s, err := schema.NewProtobufSerde(c, "names-value", `syntax = "proto3"; package google.protobuf; message DoubleValue { double value = 1; } message StringValue { string value = 1; }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.Encode(ctx, wrapperspb.String("ada"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, payload, err := schema.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload[0] == 0 {
+		t.Fatal("payload has no message index")
+	}
+
+	got := &wrapperspb.StringValue{}
+	if err := s.Decode(ctx, data, got); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, wrapperspb.String("ada")) {
+		t.Fatalf("decoded %v", got)
+	}
+}
+
+func TestNotWireFormat(t *testing.T) {
+	if _, _, err := schema.Split([]byte("{}")); !errors.Is(err, schema.ErrNotWireFormat) {
+		t.Fatalf("err = %v, want ErrNotWireFormat", err)
+	}
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	dataBucket    = []byte("data")
+	offsetsBucket = []byte("offsets")
+)
+
+// Disk keeps data in an embedded bbolt database file. It is Durable; every
+// write is synced, so batch writes with Apply where possible.
+type Disk struct {
+	db *bolt.DB
+}
+
+// OpenDisk opens or creates the database at path. Only one process may have
+// it open at a time.
+func OpenDisk(path string) (*Disk, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("store: opening %v: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{dataBucket, offsetsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Disk{db: db}, nil
+}
+
+func (d *Disk) Get(key []byte) ([]byte, bool, error) {
+	var out []byte
+	err := d.db.View(func(tx *bolt.Tx) error {
+		// values are only valid inside the transaction
+		if v := tx.Bucket(dataBucket).Get(key); v != nil {
+			out = append([]byte{}, v...)
+		}
+		return nil
+	})
+	return out, out != nil, err
+}
+
+func (d *Disk) Put(key, value []byte) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		if value == nil {
+			value = []byte{}
+		}
+		return tx.Bucket(dataBucket).Put(key, value)
+	})
+}
+
+func (d *Disk) Delete(key []byte) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(dataBucket).Delete(key)
+	})
+}
+
+// Range holds a read transaction while fn runs, so fn must not write to the
+// store.
+func (d *Disk) Range(from, to []byte, fn func(key, value []byte) bool) error {
+	return d.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(dataBucket).Cursor()
+		k, v := c.First()
+		if from != nil {
+			k, v = c.Seek(from)
+		}
+		for ; k != nil; k, v = c.Next() {
+			if to != nil && bytes.Compare(k, to) >= 0 {
+				break
+			}
+			if !fn(k, v) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+func (d *Disk) Offset(topic string, partition int) (int64, bool, error) {
+	var (
+		off int64
+		ok  bool
+	)
+	err := d.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(offsetsBucket).Get(offsetKey(topic, partition)); len(v) == 8 {
+			off, ok = int64(binary.BigEndian.Uint64(v)), true
+		}
+		return nil
+	})
+	return off, ok, err
+}
+
+func (d *Disk) Apply(topic string, partition int, next int64, changes []Change) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		if err := writeChanges(tx, changes); err != nil {
+			return err
+		}
+		return tx.Bucket(offsetsBucket).Put(offsetKey(topic, partition), binary.BigEndian.AppendUint64(nil, uint64(next)))
+	})
+}
+
+// WriteBatch writes changes in one transaction.
+func (d *Disk) WriteBatch(changes []Change) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return writeChanges(tx, changes)
+	})
+}
+
+func writeChanges(tx *bolt.Tx, changes []Change) error {
+	data := tx.Bucket(dataBucket)
+	for _, c := range changes {
+		var err error
+		if c.Value == nil {
+			err = data.Delete(c.Key)
+		} else {
+			err = data.Put(c.Key, c.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Disk) Close() error {
+	return d.db.Close()
+}
+
+func offsetKey(topic string, partition int) []byte {
+	return fmt.Appendf(nil, "%v/%v", topic, partition)
+}
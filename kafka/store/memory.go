@@ -0,0 +1,90 @@
+package store
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+)
+
+// Memory keeps everything in a map; it starts empty on every run.
+type Memory struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{data: map[string][]byte{}}
+}
+
+func (m *Memory) Get(key []byte) ([]byte, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.data[string(key)]
+	return v, ok, nil
+}
+
+func (m *Memory) Put(key, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[string(key)] = append([]byte(nil), value...)
+	return nil
+}
+
+func (m *Memory) Delete(key []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, string(key))
+	return nil
+}
+
+func (m *Memory) WriteBatch(changes []Change) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range changes {
+		if c.Value == nil {
+			delete(m.data, string(c.Key))
+		} else {
+			m.data[string(c.Key)] = append([]byte(nil), c.Value...)
+		}
+	}
+	return nil
+}
+
+// Range sorts the matching keys on every call, which is fine for the table
+// sizes kept in memory.
+func (m *Memory) Range(from, to []byte, fn func(key, value []byte) bool) error {
+	m.mu.RLock()
+	var keys []string
+	for k := range m.data {
+		if inRange([]byte(k), from, to) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	values := make([][]byte, len(keys))
+	for i, k := range keys {
+		values[i] = m.data[k]
+	}
+	m.mu.RUnlock()
+
+	for i, k := range keys {
+		if !fn([]byte(k), values[i]) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.data)
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+func inRange(key, from, to []byte) bool {
+	return (from == nil || bytes.Compare(key, from) >= 0) && (to == nil || bytes.Compare(key, to) < 0)
+}
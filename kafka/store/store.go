@@ -0,0 +1,56 @@
+// Package store holds local key/value state built from Kafka topics.
+package store
+
+// Store is a key/value store safe for concurrent use.
+type Store interface {
+	// Get returns the value of key and whether it exists.
+	Get(key []byte) ([]byte, bool, error)
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	// Range calls fn in key order for keys in [from, to); nil bounds are
+	// open. It stops early when fn returns false.
+	Range(from, to []byte, fn func(key, value []byte) bool) error
+	Close() error
+}
+
+// Change is a put, or a delete when Value is nil.
+type Change struct {
+	Key   []byte
+	Value []byte
+}
+
+// Batcher is implemented by stores that write several changes at once
+// cheaper than one by one.
+type Batcher interface {
+	WriteBatch(changes []Change) error
+}
+
+// WriteBatch applies changes to s, in one go if s is a Batcher.
+func WriteBatch(s Store, changes []Change) error {
+	if b, ok := s.(Batcher); ok {
+		return b.WriteBatch(changes)
+	}
+	for _, c := range changes {
+		var err error
+		if c.Value == nil {
+			err = s.Delete(c.Key)
+		} else {
+			err = s.Put(c.Key, c.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Durable is implemented by stores that survive restarts. They remember the
+// next offset to read per partition so that a table resumes where it
+// stopped instead of reading the topic again.
+type Durable interface {
+	Store
+	Offset(topic string, partition int) (int64, bool, error)
+	// Apply writes changes and records next as the partition's offset in
+	// one transaction.
+	Apply(topic string, partition int, next int64, changes []Change) error
+}
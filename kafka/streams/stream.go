@@ -0,0 +1,126 @@
+// Package streams builds read-transform-write pipelines between Kafka
+// topics.
+package streams
+
+import (
+	"context"
+	"producer/kafka"
+)
+
+// op processes one record and passes what it produces to next.
+type op func(ctx context.Context, m kafka.Message, next func(kafka.Message) error) error
+
+// Stream is a pipeline from a source topic through a chain of operators to
+// an optional sink topic. Offsets of source records are committed once
+// everything they produced was written to the sink.
+type Stream struct {
+	source []kafka.Option
+	sink   []kafka.Option
+	ops    []op
+	// err is the first mistake made while building the stream; Run
+	// returns it.
+	err error
+}
+
+// From starts a stream reading the topic set in opts. Set a group so that
+// progress is committed.
+func From(opts ...kafka.Option) *Stream {
+	return &Stream{source: opts}
+}
+
+func (s *Stream) then(o op) *Stream {
+	s.ops = append(s.ops, o)
+	return s
+}
+
+func (s *Stream) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Map replaces every record with fn's result.
+func (s *Stream) Map(fn func(kafka.Message) kafka.Message) *Stream {
+	return s.then(func(_ context.Context, m kafka.Message, next func(kafka.Message) error) error {
+		return next(fn(m))
+	})
+}
+
+// Filter drops records for which fn returns false.
+func (s *Stream) Filter(fn func(kafka.Message) bool) *Stream {
+	return s.then(func(_ context.Context, m kafka.Message, next func(kafka.Message) error) error {
+		if !fn(m) {
+			return nil
+		}
+		return next(m)
+	})
+}
+
+// FlatMap replaces every record with any number of records.
+func (s *Stream) FlatMap(fn func(kafka.Message) []kafka.Message) *Stream {
+	return s.then(func(_ context.Context, m kafka.Message, next func(kafka.Message) error) error {
+		for _, out := range fn(m) {
+			if err := next(out); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// To writes the stream's records to the topic set in opts.
+func (s *Stream) To(opts ...kafka.Option) *Stream {
+	s.sink = opts
+	return s
+}
+
+// Run processes records until ctx is cancelled or a step fails. A failing
+// record is not committed, so it is processed again on restart.
+func (s *Stream) Run(ctx context.Context) error {
+	if s.err != nil {
+		return s.err
+	}
+	c, err := kafka.NewConsumer(s.source...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var p *kafka.Producer
+	if s.sink != nil {
+		if p, err = kafka.NewProducer(s.sink...); err != nil {
+			return err
+		}
+		defer p.Close()
+	}
+
+	return c.Run(ctx, func(ctx context.Context, m kafka.Message) error {
+		var out []kafka.Message
+		if err := s.process(ctx, 0, m, func(m kafka.Message) error {
+			out = append(out, sinkMessage(m))
+			return nil
+		}); err != nil {
+			return err
+		}
+		if p == nil || len(out) == 0 {
+			return nil
+		}
+		// returning only after the write lets the consumer commit
+		return p.Send(ctx, out...)
+	})
+}
+
+func (s *Stream) process(ctx context.Context, i int, m kafka.Message, emit func(kafka.Message) error) error {
+	if i == len(s.ops) {
+		return emit(m)
+	}
+	return s.ops[i](ctx, m, func(m kafka.Message) error {
+		return s.process(ctx, i+1, m, emit)
+	})
+}
+
+// sinkMessage drops what belongs to the source record, so the sink picks
+// the partition and the broker the offset.
+func sinkMessage(m kafka.Message) kafka.Message {
+	return kafka.Message{Key: m.Key, Value: m.Value, Headers: m.Headers, Time: m.Time}
+}
@@ -0,0 +1,212 @@
+package streams
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"producer/kafka"
+	"producer/store"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var errNoStore = errors.New("streams: aggregation needs a state store")
+
+// Headers added to aggregation results.
+const (
+	HeaderWindowStart = "window-start"
+	HeaderWindowEnd   = "window-end"
+)
+
+// Window splits time into ranges of Size starting every Advance. Records
+// arriving more than Grace after their window ended are dropped.
+type Window struct {
+	Size    time.Duration
+	Advance time.Duration
+	Grace   time.Duration
+}
+
+// Tumbling windows do not overlap; every record belongs to one window.
+func Tumbling(size time.Duration) Window {
+	return Window{Size: size, Advance: size}
+}
+
+// Hopping windows overlap when advance is less than size; a record belongs
+// to every window covering its timestamp.
+func Hopping(size, advance time.Duration) Window {
+	return Window{Size: size, Advance: advance}
+}
+
+// validate rejects windows that can't be aligned to the millisecond
+// timestamps of records.
+func (w Window) validate() error {
+	switch {
+	case w.Size < time.Millisecond || w.Size%time.Millisecond != 0:
+		return fmt.Errorf("streams: window size %v is not a positive number of milliseconds", w.Size)
+	case w.Advance < 0 || w.Advance%time.Millisecond != 0:
+		return fmt.Errorf("streams: window advance %v is not a whole number of milliseconds", w.Advance)
+	}
+	return nil
+}
+
+// step is how far apart window starts are.
+func (w Window) step() time.Duration {
+	if w.Advance <= 0 || w.Advance > w.Size {
+		return w.Size
+	}
+	return w.Advance
+}
+
+// align returns the start of the newest window starting at or before t.
+func (w Window) align(t time.Time) time.Time {
+	// align to the unix epoch, not to Go's zero time like Truncate
+	ms, step := t.UnixMilli(), w.step().Milliseconds()
+	return time.UnixMilli(ms - ms%step)
+}
+
+// starts returns the start of every window containing t, newest first.
+func (w Window) starts(t time.Time) []time.Time {
+	advance := w.step()
+	last := w.align(t)
+	var out []time.Time
+	for start := last; start.After(t.Add(-w.Size)); start = start.Add(-advance) {
+		out = append(out, start)
+	}
+	return out
+}
+
+// Grouped is a stream whose records are aggregated per key. Aggregation
+// state is local, so records of one key must come from one partition of
+// the source, as they do when the source is keyed by the grouping key.
+type Grouped struct {
+	s *Stream
+}
+
+// GroupByKey groups records by their key.
+func (s *Stream) GroupByKey() *Grouped {
+	return &Grouped{s: s}
+}
+
+// Windowed is a grouped stream aggregated per key and window.
+type Windowed struct {
+	g *Grouped
+	w Window
+}
+
+// WindowedBy aggregates per window w. Size and Advance must be whole
+// milliseconds; an invalid window makes Run fail.
+func (g *Grouped) WindowedBy(w Window) *Windowed {
+	if err := w.validate(); err != nil {
+		g.s.fail(err)
+	}
+	return &Windowed{g: g, w: w}
+}
+
+// Aggregator folds a record's value into the aggregate of its key and
+// window; acc is nil for a new window.
+type Aggregator func(key, value, acc []byte) []byte
+
+// Aggregate keeps one aggregate per key and window in st and emits the new
+// aggregate after every update, keyed by the record key with the window
+// bounds in headers. st is written only after the results were sent, so a
+// record redelivered after a failed send is not counted twice. Windows are
+// removed from st once they can no longer change, whenever stream time
+// passes the start of another window. Record time is taken from the record
+// timestamp.
+func (wd *Windowed) Aggregate(st store.Store, agg Aggregator) *Stream {
+	w := wd.w
+	var (
+		mu         sync.Mutex
+		streamTime time.Time
+		// windows starting before expired are already removed
+		expired time.Time
+	)
+	return wd.g.s.then(func(ctx context.Context, m kafka.Message, next func(kafka.Message) error) error {
+		if st == nil {
+			return errNoStore
+		}
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := streamTime
+		if m.Time.After(now) {
+			now = m.Time
+		}
+		var (
+			outs    []kafka.Message
+			changes []store.Change
+		)
+		for _, start := range w.starts(m.Time) {
+			end := start.Add(w.Size)
+			if !end.Add(w.Grace).After(now) {
+				// window closed, the record is too late
+				continue
+			}
+			key := windowKey(start, m.Key)
+			acc, _, err := st.Get(key)
+			if err != nil {
+				return err
+			}
+			if acc = agg(m.Key, m.Value, acc); acc == nil {
+				// a nil value would delete the window
+				acc = []byte{}
+			}
+			changes = append(changes, store.Change{Key: key, Value: acc})
+
+			out := kafka.Message{Key: m.Key, Value: acc, Time: m.Time}
+			out.SetHeader(HeaderWindowStart, []byte(strconv.FormatInt(start.UnixMilli(), 10)))
+			out.SetHeader(HeaderWindowEnd, []byte(strconv.FormatInt(end.UnixMilli(), 10)))
+			outs = append(outs, out)
+		}
+		for _, out := range outs {
+			if err := next(out); err != nil {
+				return err
+			}
+		}
+
+		cutoff := w.align(now.Add(-w.Size - w.Grace))
+		expire := cutoff.UnixMilli() > 0 && cutoff.After(expired)
+		if expire {
+			old, err := expiredWindows(st, cutoff)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, old...)
+		}
+		if err := store.WriteBatch(st, changes); err != nil {
+			return err
+		}
+		streamTime = now
+		if expire {
+			expired = cutoff
+		}
+		return nil
+	})
+}
+
+// Count counts records per key and window, as decimal strings.
+func (wd *Windowed) Count(st store.Store) *Stream {
+	return wd.Aggregate(st, func(_, _, acc []byte) []byte {
+		n, _ := strconv.ParseInt(string(acc), 10, 64)
+		return strconv.AppendInt(nil, n+1, 10)
+	})
+}
+
+// windowKey sorts state by window start, so expired windows form a prefix.
+func windowKey(start time.Time, key []byte) []byte {
+	out := binary.BigEndian.AppendUint64(nil, uint64(start.UnixMilli()))
+	return append(out, key...)
+}
+
+// expiredWindows returns deletes of the state of windows that started
+// before cutoff.
+func expiredWindows(st store.Store, cutoff time.Time) ([]store.Change, error) {
+	var old []store.Change
+	err := st.Range(nil, binary.BigEndian.AppendUint64(nil, uint64(cutoff.UnixMilli())), func(k, _ []byte) bool {
+		old = append(old, store.Change{Key: append([]byte(nil), k...)})
+		return true
+	})
+	return old, err
+}
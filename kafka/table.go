@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"producer/kafka"
+	"producer/store"
+	"strings"
+)
+
+// tableCommand runs the "table" command: it materializes a compacted topic,
+// prints it once caught up, and with -follow keeps it updated.
+func tableCommand(ctx context.Context, args []string) error {
+	var (
+		brokers string
+		topicN  string
+		db      string
+		follow  bool
+	)
+	fs := flag.NewFlagSet("table", flag.ExitOnError)
+	fs.StringVar(&brokers, "brokers", url, "comma separated broker addresses")
+	fs.StringVar(&topicN, "topic", topic, "compacted topic to read")
+	fs.StringVar(&db, "db", "", "keep the table in this file instead of in memory")
+	fs.BoolVar(&follow, "follow", false, "keep the table updated after printing it")
+	fs.Parse(args)
+
+	var s store.Store = store.NewMemory()
+	if db != "" {
+		var err error
+		if s, err = store.OpenDisk(db); err != nil {
+			return err
+		}
+	}
+	defer s.Close()
+
+	t, err := kafka.NewTable(s,
+		kafka.WithBrokers(strings.Split(brokers, ",")...),
+		kafka.WithTopic(topicN),
+	)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- t.Run(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-t.Ready():
+	}
+
+	if keys := fs.Args(); len(keys) > 0 {
+		for _, k := range keys {
+			v, ok, err := t.Get([]byte(k))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Printf("%v: not found\n", k)
+				continue
+			}
+			fmt.Printf("%v = %s\n", k, v)
+		}
+	} else {
+		n := 0
+		err := t.Range(nil, nil, func(k, v []byte) bool {
+			fmt.Printf("%s = %s\n", k, v)
+			n++
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%v keys\n", n)
+	}
+
+	if !follow {
+		cancel()
+		return <-done
+	}
+	log.Printf("caught up, following %v", topicN)
+	return <-done
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"producer/kafka"
+	"strconv"
+	"strings"
+)
+
+// tailCommand runs the "tail" command, which prints the records of chosen
+// partitions without joining a group, so it never moves any group's offsets.
+func tailCommand(ctx context.Context, args []string) error {
+	var (
+		brokers    string
+		topicN     string
+		partitions string
+		from       string
+		limit      int
+	)
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	fs.StringVar(&brokers, "brokers", url, "comma separated broker addresses")
+	fs.StringVar(&topicN, "topic", topic, "topic to read")
+	fs.StringVar(&partitions, "partitions", "", "comma separated partitions, all if empty")
+	fs.StringVar(&from, "from", "latest", "earliest, latest or an offset")
+	fs.IntVar(&limit, "n", 0, "stop after this many records, 0 to follow")
+	fs.Parse(args)
+
+	offset, err := parseStartOffset(from)
+	if err != nil {
+		return err
+	}
+	ids, err := parseInts(partitions)
+	if err != nil {
+		return err
+	}
+	opts := []kafka.Option{
+		kafka.WithBrokers(strings.Split(brokers, ",")...),
+		kafka.WithTopic(topicN),
+	}
+	if len(ids) == 0 {
+		admin, err := kafka.NewAdmin(opts...)
+		if err != nil {
+			return err
+		}
+		info, err := admin.DescribeTopic(ctx, topicN)
+		admin.Close()
+		if err != nil {
+			return err
+		}
+		for _, p := range info.Partitions {
+			ids = append(ids, p.ID)
+		}
+	}
+
+	var assignment []kafka.Assignment
+	for _, id := range ids {
+		assignment = append(assignment, kafka.Assignment{Partition: id, Offset: offset})
+	}
+	consumer, err := kafka.NewConsumer(append(opts,
+		kafka.WithAssignment(assignment...),
+		kafka.WithRebalanceHooks(kafka.RebalanceHooks{
+			OnAssign: func(ctx context.Context, partitions []int) error {
+				fmt.Printf("reading %v partitions %v\n", topicN, joinInts(partitions))
+				return nil
+			},
+		}),
+	)...)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	n := 0
+	return consumer.Run(ctx, func(ctx context.Context, m kafka.Message) error {
+		fmt.Printf("%v/%v %v %s = %s\n", m.Partition, m.Offset, m.Time.Format("15:04:05.000"), m.Key, m.Value)
+		if n++; limit > 0 && n >= limit {
+			cancel()
+		}
+		return nil
+	})
+}
+
+func parseStartOffset(s string) (int64, error) {
+	switch s {
+	case "earliest":
+		return kafka.FirstOffset, nil
+	case "latest":
+		return kafka.LastOffset, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bad start offset %q, want earliest, latest or an offset", s)
+	}
+	return n, nil
+}
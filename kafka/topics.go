@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"producer/kafka"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// usernames is the topic the queue and pubsub demos use. Producers ensure it
+// on start, so no topic manifest is needed in the cluster.
+var usernames = kafka.TopicSpec{
+	Name:              topic,
+	Partitions:        3,
+	ReplicationFactor: 1,
+	Configs: map[string]string{
+		"retention.ms": fmt.Sprint((7 * 24 * time.Hour).Milliseconds()),
+	},
+}
+
+const topicUsage = `usage: producer topic <command> [flags] [name]
+
+commands:
+  list                      list topics
+  describe NAME             show partitions and configs
+  create NAME               create a topic
+  alter NAME                add partitions and set configs
+  delete NAME               delete a topic
+  ensure NAME               create or alter the topic to match the flags`
+
+// configFlag collects repeated -config key=value flags.
+type configFlag map[string]string
+
+func (c configFlag) String() string {
+	return fmt.Sprint(map[string]string(c))
+}
+
+func (c configFlag) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("config %q is not key=value", s)
+	}
+	c[k] = v
+	return nil
+}
+
+// topicCommand runs the "topic" command.
+func topicCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New(topicUsage)
+	}
+	cmd := args[0]
+
+	var (
+		brokers     string
+		partitions  int
+		replication int
+		jsonOut     bool
+		configs     = configFlag{}
+	)
+	fs := flag.NewFlagSet("topic "+cmd, flag.ExitOnError)
+	fs.StringVar(&brokers, "brokers", url, "comma separated broker addresses")
+	fs.IntVar(&partitions, "partitions", 0, "number of partitions")
+	fs.IntVar(&replication, "replication", 0, "replication factor when creating, 0 for the broker default")
+	fs.Var(configs, "config", "topic config as key=value, repeatable; an empty value resets it")
+	fs.BoolVar(&jsonOut, "json", false, "print describe output as JSON")
+	fs.Parse(args[1:])
+
+	admin, err := kafka.NewAdmin(kafka.WithBrokers(strings.Split(brokers, ",")...))
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	if cmd == "list" {
+		names, err := admin.ListTopics(ctx)
+		if err != nil {
+			return err
+		}
+		for _, n := range names {
+			fmt.Println(n)
+		}
+		return nil
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New(topicUsage)
+	}
+	spec := kafka.TopicSpec{
+		Name:              fs.Arg(0),
+		Partitions:        partitions,
+		ReplicationFactor: replication,
+		Configs:           configs,
+	}
+
+	switch cmd {
+	case "describe":
+		info, err := admin.DescribeTopic(ctx, spec.Name)
+		if err != nil {
+			return err
+		}
+		if jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(info)
+		}
+		printTopic(info)
+		return nil
+	case "create":
+		return admin.CreateTopic(ctx, spec)
+	case "alter":
+		if partitions > 0 {
+			if err := admin.SetPartitions(ctx, spec.Name, partitions); err != nil {
+				return err
+			}
+		}
+		return admin.AlterConfigs(ctx, spec.Name, configs)
+	case "delete":
+		return admin.DeleteTopic(ctx, spec.Name)
+	case "ensure":
+		return admin.EnsureTopic(ctx, spec)
+	default:
+		return fmt.Errorf("unknown topic command %q\n%v", cmd, topicUsage)
+	}
+}
+
+func printTopic(info kafka.TopicInfo) {
+	fmt.Printf("topic %v, %v partitions\n\n", info.Name, len(info.Partitions))
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PARTITION\tLEADER\tREPLICAS\tISR")
+	for _, p := range info.Partitions {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", p.ID, p.Leader, joinInts(p.Replicas), joinInts(p.ISR))
+	}
+	tw.Flush()
+
+	fmt.Println()
+	tw = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONFIG\tVALUE\tSOURCE")
+	for _, c := range info.Configs {
+		source := "default"
+		if c.Override {
+			source = "topic"
+		}
+		value := c.Value
+		if c.Sensitive {
+			value = "<hidden>"
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", c.Name, value, source)
+	}
+	tw.Flush()
+}
+
+func joinInts(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = fmt.Sprint(id)
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"admin/management"
+	"admin/plan"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"publisher/logger"
+	"publisher/rabbitmq"
+	"publisher/rabbitmq/publisher"
+	"publisher/utils"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	log := logger.Get()
+
+	if err := godotenv.Load(); err != nil {
+		log.Warn("no .env file loaded", "error", err)
+	}
+
+	var (
+		url       string
+		mgmtURL   string
+		vhost     string
+		source    string
+		prune     bool
+		jsonOut   bool
+		exitCodes bool
+	)
+	flag.StringVar(&url, "url", os.Getenv(utils.URL), "amqp url, defaults to the url env variable")
+	flag.StringVar(&mgmtURL, "mgmt-url", os.Getenv(utils.MGMT_URL), "management api url, defaults to the mgmt_url env variable or port 15672 on the amqp host")
+	flag.StringVar(&vhost, "vhost", "", "vhost to compare, defaults to the vhost in the amqp url")
+	flag.StringVar(&source, "source", "management", "where to read current state from: management or amqp")
+	flag.BoolVar(&prune, "prune", false, "also plan deletion of exchanges, queues and bindings not in the desired topology")
+	flag.BoolVar(&jsonOut, "json", false, "print the plan as JSON")
+	flag.BoolVar(&exitCodes, "detailed-exitcode", false, "exit with 2 when there are changes")
+	flag.Parse()
+
+	client, urlVhost, err := management.FromAMQPURL(url, mgmtURL)
+	if err != nil {
+		log.Fatal("invalid amqp url", "error", err)
+	}
+	if vhost == "" {
+		vhost = urlVhost
+	}
+
+	desired := publisher.Topology()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var state plan.State
+	switch source {
+	case "management":
+		state, err = plan.FromManagement(ctx, client, vhost)
+	case "amqp":
+		rbmq := rabbitmq.Rabbitmq{
+			Url:         url,
+			Credentials: rabbitmq.CredentialsFromEnv(),
+		}
+		if err = rbmq.Init(); err != nil {
+			break
+		}
+		defer rbmq.Connection.Close()
+		state, err = plan.FromAMQP(rbmq.Connection, desired)
+	default:
+		err = fmt.Errorf("unknown source %q", source)
+	}
+	if err != nil {
+		log.Fatal("error reading broker state", "error", err)
+	}
+
+	p := plan.Diff(desired, state, plan.Options{Prune: prune})
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(p)
+	} else {
+		err = p.Write(os.Stdout)
+	}
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if exitCodes && len(p.Changes) > 0 {
+		os.Exit(2)
+	}
+}
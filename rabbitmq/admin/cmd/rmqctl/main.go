@@ -0,0 +1,136 @@
+package main
+
+import (
+	"admin/management"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"publisher/logger"
+	"publisher/utils"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `usage: rmqctl [flags] <command> [name]
+
+commands:
+  overview      cluster versions and object/message totals
+  nodes         node health and resource usage
+  vhosts        virtual hosts with message counts
+  exchanges     exchanges in the vhost
+  queues        queues with depth and rates
+  queue <name>  a single queue and its bindings
+  bindings      bindings in the vhost
+  consumers     consumers and their prefetch
+  connections   open client connections
+
+flags:
+`
+
+func main() {
+	log := logger.Get()
+
+	if err := godotenv.Load(); err != nil {
+		log.Warn("no .env file loaded", "error", err)
+	}
+
+	var (
+		url     string
+		mgmtURL string
+		vhost   string
+		all     bool
+		output  string
+		timeout time.Duration
+	)
+	flag.StringVar(&url, "url", os.Getenv(utils.URL), "amqp url whose credentials are used, defaults to the url env variable")
+	flag.StringVar(&mgmtURL, "mgmt-url", os.Getenv(utils.MGMT_URL), "management api url, defaults to the mgmt_url env variable or port 15672 on the amqp host")
+	flag.StringVar(&vhost, "vhost", "", "vhost to inspect, defaults to the vhost in the amqp url")
+	flag.BoolVar(&all, "all", false, "list objects across every vhost")
+	flag.StringVar(&output, "o", "table", "output format: table or json")
+	flag.DurationVar(&timeout, "timeout", 10*time.Second, "request timeout")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client, urlVhost, err := management.FromAMQPURL(url, mgmtURL)
+	if err != nil {
+		log.Fatal("invalid amqp url", "error", err)
+	}
+	if vhost == "" {
+		vhost = urlVhost
+	}
+	if all {
+		vhost = ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := run(ctx, client, vhost, output, flag.Args(), os.Stdout); err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+func run(ctx context.Context, c *management.Client, vhost, output string, args []string, w io.Writer) error {
+	var (
+		v   any
+		err error
+	)
+	switch cmd := args[0]; cmd {
+	case "overview":
+		v, err = c.Overview(ctx)
+	case "nodes":
+		v, err = c.Nodes(ctx)
+	case "vhosts":
+		v, err = c.Vhosts(ctx)
+	case "exchanges":
+		v, err = c.Exchanges(ctx, vhost)
+	case "queues":
+		v, err = c.Queues(ctx, vhost)
+	case "queue":
+		if len(args) < 2 {
+			return fmt.Errorf("queue needs a queue name")
+		}
+		if vhost == "" {
+			return fmt.Errorf("queue needs a vhost")
+		}
+		var d queueDetail
+		if d.Queue, err = c.Queue(ctx, vhost, args[1]); err != nil {
+			return err
+		}
+		d.Bindings, err = c.QueueBindings(ctx, vhost, args[1])
+		v = d
+	case "bindings":
+		v, err = c.Bindings(ctx, vhost)
+	case "consumers":
+		v, err = c.Consumers(ctx, vhost)
+	case "connections":
+		v, err = c.Connections(ctx, vhost)
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch output {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "table":
+		return writeTable(w, v)
+	}
+	return fmt.Errorf("unknown output format %q", output)
+}
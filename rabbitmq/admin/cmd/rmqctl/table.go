@@ -0,0 +1,168 @@
+package main
+
+import (
+	"admin/management"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type queueDetail struct {
+	Queue    management.Queue     `json:"queue"`
+	Bindings []management.Binding `json:"bindings"`
+}
+
+func writeTable(w io.Writer, v any) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	switch v := v.(type) {
+	case management.Overview:
+		fmt.Fprintf(tw, "cluster\t%v\n", v.ClusterName)
+		fmt.Fprintf(tw, "node\t%v\n", v.Node)
+		fmt.Fprintf(tw, "rabbitmq\t%v\n", v.RabbitMQVersion)
+		fmt.Fprintf(tw, "erlang\t%v\n", v.ErlangVersion)
+		fmt.Fprintf(tw, "connections\t%v\n", v.ObjectTotals.Connections)
+		fmt.Fprintf(tw, "channels\t%v\n", v.ObjectTotals.Channels)
+		fmt.Fprintf(tw, "exchanges\t%v\n", v.ObjectTotals.Exchanges)
+		fmt.Fprintf(tw, "queues\t%v\n", v.ObjectTotals.Queues)
+		fmt.Fprintf(tw, "consumers\t%v\n", v.ObjectTotals.Consumers)
+		fmt.Fprintf(tw, "messages\t%v (ready %v, unacked %v)\n",
+			v.QueueTotals.Messages, v.QueueTotals.MessagesReady, v.QueueTotals.MessagesUnacknowledged)
+		fmt.Fprintf(tw, "publish/s\t%.1f\n", v.MessageStats.PublishDetails.Rate)
+		fmt.Fprintf(tw, "deliver/s\t%.1f\n", v.MessageStats.DeliverGetDetails.Rate)
+
+	case []management.Node:
+		fmt.Fprintln(tw, "NAME\tTYPE\tRUNNING\tUPTIME\tMEMORY\tDISK FREE\tFDS\tSOCKETS\tALARMS")
+		for _, n := range v {
+			var alarms []string
+			if n.MemAlarm {
+				alarms = append(alarms, "memory")
+			}
+			if n.DiskFreeAlarm {
+				alarms = append(alarms, "disk")
+			}
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v/%v\t%v\t%v/%v\t%v/%v\t%v\n",
+				n.Name, n.Type, n.Running, (time.Duration(n.Uptime) * time.Millisecond).Round(time.Second),
+				bytes(n.MemUsed), bytes(n.MemLimit), bytes(n.DiskFree),
+				n.FDUsed, n.FDTotal, n.SocketsUsed, n.SocketsTotal, strings.Join(alarms, ","))
+		}
+
+	case []management.Vhost:
+		fmt.Fprintln(tw, "NAME\tMESSAGES\tREADY\tUNACKED\tDESCRIPTION")
+		for _, h := range v {
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n",
+				h.Name, h.Messages, h.MessagesReady, h.MessagesUnacknowledged, h.Description)
+		}
+
+	case []management.Exchange:
+		sort.Slice(v, func(i, j int) bool { return v[i].Vhost+v[i].Name < v[j].Vhost+v[j].Name })
+		fmt.Fprintln(tw, "VHOST\tNAME\tTYPE\tDURABLE\tAUTO DELETE\tINTERNAL\tARGUMENTS")
+		for _, e := range v {
+			name := e.Name
+			if name == "" {
+				name = "(default)"
+			}
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+				e.Vhost, name, e.Type, e.Durable, e.AutoDelete, e.Internal, args(e.Arguments))
+		}
+
+	case []management.Queue:
+		sort.Slice(v, func(i, j int) bool { return v[i].Vhost+v[i].Name < v[j].Vhost+v[j].Name })
+		fmt.Fprintln(tw, "VHOST\tNAME\tTYPE\tSTATE\tMESSAGES\tREADY\tUNACKED\tCONSUMERS\tPUBLISH/S\tDELIVER/S\tACK/S")
+		for _, q := range v {
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%.1f\t%.1f\t%.1f\n",
+				q.Vhost, q.Name, q.Type, q.State, q.Messages, q.MessagesReady, q.MessagesUnacknowledged, q.Consumers,
+				q.MessageStats.PublishDetails.Rate, q.MessageStats.DeliverGetDetails.Rate, q.MessageStats.AckDetails.Rate)
+		}
+
+	case queueDetail:
+		q := v.Queue
+		fmt.Fprintf(tw, "name\t%v\n", q.Name)
+		fmt.Fprintf(tw, "vhost\t%v\n", q.Vhost)
+		fmt.Fprintf(tw, "type\t%v\n", q.Type)
+		fmt.Fprintf(tw, "state\t%v\n", q.State)
+		fmt.Fprintf(tw, "node\t%v\n", q.Node)
+		fmt.Fprintf(tw, "durable\t%v\n", q.Durable)
+		fmt.Fprintf(tw, "arguments\t%v\n", args(q.Arguments))
+		fmt.Fprintf(tw, "policy\t%v\n", q.Policy)
+		fmt.Fprintf(tw, "messages\t%v (ready %v, unacked %v)\n", q.Messages, q.MessagesReady, q.MessagesUnacknowledged)
+		fmt.Fprintf(tw, "message bytes\t%v\n", bytes(q.MessageBytes))
+		fmt.Fprintf(tw, "memory\t%v\n", bytes(q.Memory))
+		fmt.Fprintf(tw, "consumers\t%v (utilisation %.0f%%)\n", q.Consumers, q.ConsumerUtilisation*100)
+		fmt.Fprintf(tw, "publish/s\t%.1f\n", q.MessageStats.PublishDetails.Rate)
+		fmt.Fprintf(tw, "deliver/s\t%.1f\n", q.MessageStats.DeliverGetDetails.Rate)
+		fmt.Fprintf(tw, "ack/s\t%.1f\n", q.MessageStats.AckDetails.Rate)
+		fmt.Fprintf(tw, "redeliver/s\t%.1f\n", q.MessageStats.RedeliverDetails.Rate)
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		return writeTable(w, v.Bindings)
+
+	case []management.Binding:
+		fmt.Fprintln(tw, "VHOST\tSOURCE\tDESTINATION\tTYPE\tROUTING KEY\tARGUMENTS")
+		for _, b := range v {
+			source := b.Source
+			if source == "" {
+				source = "(default)"
+			}
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\n",
+				b.Vhost, source, b.Destination, b.DestinationType, b.RoutingKey, args(b.Arguments))
+		}
+
+	case []management.Consumer:
+		fmt.Fprintln(tw, "VHOST\tQUEUE\tTAG\tCONNECTION\tACK\tPREFETCH\tEXCLUSIVE\tACTIVE")
+		for _, c := range v {
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+				c.Queue.Vhost, c.Queue.Name, c.ConsumerTag, c.ChannelDetails.ConnectionName,
+				c.AckRequired, c.PrefetchCount, c.Exclusive, c.Active)
+		}
+
+	case []management.Connection:
+		fmt.Fprintln(tw, "NAME\tVHOST\tUSER\tSTATE\tPROTOCOL\tCHANNELS\tSSL\tRECV\tSENT\tCONNECTED")
+		for _, c := range v {
+			connected := ""
+			if c.ConnectedAt > 0 {
+				connected = time.UnixMilli(c.ConnectedAt).Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+				c.Name, c.Vhost, c.User, c.State, c.Protocol, c.Channels, c.SSL,
+				bytes(c.RecvOct), bytes(c.SendOct), connected)
+		}
+
+	default:
+		return fmt.Errorf("no table format for %T", v)
+	}
+	return tw.Flush()
+}
+
+func args(a map[string]any) string {
+	if len(a) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%v=%v", k, a[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+func bytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%vB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
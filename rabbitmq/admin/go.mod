@@ -0,0 +1,16 @@
+module admin
+
+go 1.24.7
+
+require (
+	github.com/joho/godotenv v1.5.1
+	github.com/rabbitmq/amqp091-go v1.10.0
+	publisher v0.0.0
+)
+
+require (
+	go.uber.org/multierr v1.11.0 // indirect
+	go.uber.org/zap v1.27.1 // indirect
+)
+
+replace publisher => ../publisher
@@ -0,0 +1,105 @@
+// Package management is a client for the RabbitMQ management HTTP API.
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type Client struct {
+	BaseURL    string
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the management API at baseURL, e.g.
+// http://localhost:15672.
+func NewClient(baseURL, username, password string) *Client {
+	return &Client{
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		Username: username,
+		Password: password,
+	}
+}
+
+// Error is returned for non 2xx responses.
+type Error struct {
+	StatusCode int
+	Reason     string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("management api returned %v: %v", e.StatusCode, e.Reason)
+}
+
+// IsNotFound reports whether err is a 404 from the management API.
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+	req.Header.Set("Accept", "application/json")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		var body struct {
+			Error  string `json:"error"`
+			Reason string `json:"reason"`
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		reason := strings.TrimSpace(string(raw))
+		if json.Unmarshal(raw, &body) == nil && body.Reason != "" {
+			reason = body.Reason
+		}
+		return &Error{StatusCode: resp.StatusCode, Reason: reason}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// vhostPath escapes a vhost for use as a path segment; the default vhost
+// "/" becomes %2F.
+func vhostPath(vhost string) string {
+	return url.PathEscape(vhost)
+}
+
+// FromAMQPURL builds a client from an amqp url, reusing its credentials. If
+// mgmtURL is empty the API is assumed on port 15672 of the amqp host. The
+// vhost named in the amqp url is returned alongside.
+func FromAMQPURL(amqpURL, mgmtURL string) (*Client, string, error) {
+	uri, err := amqp.ParseURI(amqpURL)
+	if err != nil {
+		return nil, "", err
+	}
+	if mgmtURL == "" {
+		scheme := "http"
+		if uri.Scheme == "amqps" {
+			scheme = "https"
+		}
+		mgmtURL = scheme + "://" + net.JoinHostPort(uri.Host, "15672")
+	}
+	return NewClient(mgmtURL, uri.Username, uri.Password), uri.Vhost, nil
+}
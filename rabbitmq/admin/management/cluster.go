@@ -0,0 +1,39 @@
+package management
+
+import "context"
+
+func (c *Client) Overview(ctx context.Context) (Overview, error) {
+	var out Overview
+	err := c.get(ctx, "/api/overview", &out)
+	return out, err
+}
+
+func (c *Client) Nodes(ctx context.Context) ([]Node, error) {
+	var out []Node
+	err := c.get(ctx, "/api/nodes", &out)
+	return out, err
+}
+
+func (c *Client) Vhosts(ctx context.Context) ([]Vhost, error) {
+	var out []Vhost
+	err := c.get(ctx, "/api/vhosts", &out)
+	return out, err
+}
+
+// Consumers lists the consumers in vhost, or in every vhost if it is empty.
+func (c *Client) Consumers(ctx context.Context, vhost string) ([]Consumer, error) {
+	var out []Consumer
+	err := c.get(ctx, listPath("consumers", vhost), &out)
+	return out, err
+}
+
+// Connections lists open connections, filtered to vhost when it is set.
+func (c *Client) Connections(ctx context.Context, vhost string) ([]Connection, error) {
+	var out []Connection
+	path := "/api/connections"
+	if vhost != "" {
+		path = "/api/vhosts/" + vhostPath(vhost) + "/connections"
+	}
+	err := c.get(ctx, path, &out)
+	return out, err
+}
@@ -0,0 +1,203 @@
+package management_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"admin/management"
+	"admin/management/mgmttest"
+)
+
+func TestOverviewAndNodes(t *testing.T) {
+	srv := mgmttest.NewServer()
+	defer srv.Close()
+	srv.SetNodes(
+		management.Node{Name: "rabbit@a", Type: "disc", Running: true, MemUsed: 100, MemLimit: 1000},
+		management.Node{Name: "rabbit@b", Type: "disc", Running: false},
+	)
+	srv.AddQueue(management.Queue{Name: "q", Vhost: "/", Messages: 7, MessagesReady: 5, MessagesUnacknowledged: 2})
+	srv.AddConnection(management.Connection{Name: "c1", Vhost: "/", Channels: 3})
+	c := srv.Client()
+	ctx := context.Background()
+
+	o, err := c.Overview(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.ClusterName != "rabbit@mgmttest" || o.RabbitMQVersion == "" {
+		t.Fatalf("overview %+v", o)
+	}
+	if o.ObjectTotals.Queues != 1 || o.ObjectTotals.Connections != 1 || o.ObjectTotals.Channels != 3 || o.QueueTotals.Messages != 7 {
+		t.Fatalf("totals %+v %+v", o.ObjectTotals, o.QueueTotals)
+	}
+
+	nodes, err := c.Nodes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes[0].Name != "rabbit@a" || !nodes[0].Running || nodes[0].MemLimit != 1000 || nodes[1].Running {
+		t.Fatalf("nodes %+v", nodes)
+	}
+}
+
+func TestVhostObjects(t *testing.T) {
+	srv := mgmttest.NewServer()
+	defer srv.Close()
+	srv.AddVhost("dev")
+	srv.AddExchange(management.Exchange{Name: "orders", Vhost: "/", Type: "topic", Durable: true})
+	srv.AddQueue(management.Queue{Name: "created", Vhost: "/", Durable: true, Arguments: map[string]any{"x-queue-type": "quorum"}, MessagesReady: 4})
+	srv.AddQueue(management.Queue{Name: "other", Vhost: "dev"})
+	srv.AddBinding(management.Binding{Source: "orders", Vhost: "/", Destination: "created", RoutingKey: "order.created"})
+	srv.AddConsumer(management.Consumer{ConsumerTag: "ctag", Queue: management.QueueRef{Name: "created", Vhost: "/"}, PrefetchCount: 10})
+	srv.AddConnection(management.Connection{Name: "c1", Vhost: "/"})
+	srv.AddConnection(management.Connection{Name: "c2", Vhost: "dev"})
+	c := srv.Client()
+	ctx := context.Background()
+
+	vhosts, err := c.Vhosts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vhosts) != 2 || vhosts[0].Name != "/" || vhosts[0].MessagesReady != 4 || vhosts[1].Name != "dev" {
+		t.Fatalf("vhosts %+v", vhosts)
+	}
+
+	exchanges, err := c.Exchanges(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := c.Exchanges(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2*len(exchanges)-1 {
+		t.Fatalf("%v exchanges across vhosts, %v in /", len(all), len(exchanges))
+	}
+	e, err := c.Exchange(ctx, "/", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != "topic" || !e.Durable {
+		t.Fatalf("exchange %+v", e)
+	}
+
+	queues, err := c.Queues(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queues) != 1 || queues[0].Name != "created" || queues[0].Type != "quorum" || queues[0].Consumers != 1 {
+		t.Fatalf("queues %+v", queues)
+	}
+	q, err := c.Queue(ctx, "dev", "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Vhost != "dev" {
+		t.Fatalf("queue %+v", q)
+	}
+
+	bindings, err := c.Bindings(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the default exchange binding and the orders binding
+	if len(bindings) != 2 {
+		t.Fatalf("bindings %+v", bindings)
+	}
+	qb, err := c.QueueBindings(ctx, "/", "created")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(qb) != 2 || qb[1].Source != "orders" || qb[1].RoutingKey != "order.created" {
+		t.Fatalf("queue bindings %+v", qb)
+	}
+
+	consumers, err := c.Consumers(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(consumers) != 1 || consumers[0].ConsumerTag != "ctag" || consumers[0].PrefetchCount != 10 {
+		t.Fatalf("consumers %+v", consumers)
+	}
+
+	conns, err := c.Connections(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conns) != 2 {
+		t.Fatalf("connections %+v", conns)
+	}
+	conns, err = c.Connections(ctx, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conns) != 1 || conns[0].Name != "c2" {
+		t.Fatalf("dev connections %+v", conns)
+	}
+}
+
+func TestNamesAreEscaped(t *testing.T) {
+	srv := mgmttest.NewServer()
+	defer srv.Close()
+	// slashes, spaces and percent signs must stay inside one path segment
+	vhost, name := "team/a b", "orders/eu 100%"
+	srv.AddExchange(management.Exchange{Name: name, Vhost: vhost, Type: "fanout"})
+	srv.AddQueue(management.Queue{Name: name, Vhost: vhost})
+	c := srv.Client()
+	ctx := context.Background()
+
+	e, err := c.Exchange(ctx, vhost, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Name != name || e.Vhost != vhost {
+		t.Fatalf("exchange %+v", e)
+	}
+	q, err := c.Queue(ctx, vhost, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Name != name {
+		t.Fatalf("queue %+v", q)
+	}
+	qb, err := c.QueueBindings(ctx, vhost, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(qb) != 1 || qb[0].Destination != name {
+		t.Fatalf("queue bindings %+v", qb)
+	}
+	if _, err := c.Queue(ctx, "team", "a b/"+name); !management.IsNotFound(err) {
+		t.Fatalf("err = %v, want not found", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := mgmttest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+
+	_, err := srv.Client().Queues(ctx, "missing")
+	if !management.IsNotFound(err) {
+		t.Fatalf("err = %v, want not found", err)
+	}
+
+	_, err = management.NewClient(srv.URL, "guest", "wrong").Overview(ctx)
+	var merr *management.Error
+	if !errors.As(err, &merr) || merr.StatusCode != http.StatusUnauthorized || merr.Reason != "Login failed" {
+		t.Fatalf("err = %v, want 401 Login failed", err)
+	}
+
+	// a proxy in front of the API answers with plain text
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream unavailable", http.StatusServiceUnavailable)
+	}))
+	defer proxy.Close()
+	_, err = management.NewClient(proxy.URL, "guest", "guest").Nodes(ctx)
+	if !errors.As(err, &merr) || merr.StatusCode != http.StatusServiceUnavailable || merr.Reason != "upstream unavailable" {
+		t.Fatalf("err = %v, want 503 with the body as reason", err)
+	}
+}
@@ -0,0 +1,385 @@
+// Package mgmttest provides an in-memory stand-in for the RabbitMQ
+// management HTTP API.
+package mgmttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	"admin/management"
+)
+
+const (
+	Username = "guest"
+	Password = "guest"
+)
+
+// Server serves the management API from in-memory state. A fresh server
+// holds the default vhost "/" with the exchanges a new broker declares.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	vhosts   map[string]*vhost
+	order    []string
+	overview management.Overview
+	nodes    []management.Node
+}
+
+type vhost struct {
+	info        management.Vhost
+	exchanges   []management.Exchange
+	queues      []management.Queue
+	bindings    []management.Binding
+	consumers   []management.Consumer
+	connections []management.Connection
+}
+
+func NewServer() *Server {
+	s := &Server{
+		vhosts: map[string]*vhost{},
+		overview: management.Overview{
+			ClusterName:       "rabbit@mgmttest",
+			Node:              "rabbit@mgmttest",
+			ManagementVersion: "3.13.0",
+			RabbitMQVersion:   "3.13.0",
+			ErlangVersion:     "26.2",
+		},
+		nodes: []management.Node{{
+			Name:    "rabbit@mgmttest",
+			Type:    "disc",
+			Running: true,
+		}},
+	}
+	s.AddVhost("/")
+
+	s.Server = httptest.NewServer(s.auth(http.HandlerFunc(s.route)))
+	return s
+}
+
+// Client returns a management client authenticated against s.
+func (s *Server) Client() *management.Client {
+	return management.NewClient(s.URL, Username, Password)
+}
+
+// AddVhost creates name with the default and amq.* exchanges.
+func (s *Server) AddVhost(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vhost(name)
+}
+
+// vhost returns name, creating it if needed. s.mu must be held.
+func (s *Server) vhost(name string) *vhost {
+	if v, ok := s.vhosts[name]; ok {
+		return v
+	}
+	v := &vhost{info: management.Vhost{Name: name, Description: "Default virtual host"}}
+	if name != "/" {
+		v.info.Description = ""
+	}
+	for _, e := range []struct{ name, typ string }{
+		{"", "direct"},
+		{"amq.direct", "direct"},
+		{"amq.fanout", "fanout"},
+		{"amq.headers", "headers"},
+		{"amq.match", "headers"},
+		{"amq.rabbitmq.trace", "topic"},
+		{"amq.topic", "topic"},
+	} {
+		v.exchanges = append(v.exchanges, management.Exchange{
+			Name:      e.name,
+			Vhost:     name,
+			Type:      e.typ,
+			Durable:   true,
+			Internal:  e.name == "amq.rabbitmq.trace",
+			Arguments: map[string]any{},
+		})
+	}
+	s.vhosts[name] = v
+	s.order = append(s.order, name)
+	return v
+}
+
+// SetOverview replaces the cluster identity returned by /api/overview.
+// Object and queue totals are always computed from the server's state.
+func (s *Server) SetOverview(o management.Overview) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overview = o
+}
+
+// SetNodes replaces the nodes returned by /api/nodes.
+func (s *Server) SetNodes(nodes ...management.Node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes = nodes
+}
+
+func (s *Server) AddExchange(e management.Exchange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.Arguments == nil {
+		e.Arguments = map[string]any{}
+	}
+	v := s.vhost(e.Vhost)
+	v.exchanges = append(v.exchanges, e)
+}
+
+// AddQueue adds q and, like the broker, binds it to the default exchange.
+func (s *Server) AddQueue(q management.Queue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if q.Arguments == nil {
+		q.Arguments = map[string]any{}
+	}
+	if q.State == "" {
+		q.State = "running"
+	}
+	if q.Type == "" {
+		q.Type = "classic"
+		if t, ok := q.Arguments["x-queue-type"].(string); ok {
+			q.Type = t
+		}
+	}
+	v := s.vhost(q.Vhost)
+	v.queues = append(v.queues, q)
+	v.bindings = append(v.bindings, management.Binding{
+		Source:          "",
+		Vhost:           q.Vhost,
+		Destination:     q.Name,
+		DestinationType: "queue",
+		RoutingKey:      q.Name,
+		Arguments:       map[string]any{},
+		PropertiesKey:   q.Name,
+	})
+}
+
+func (s *Server) AddBinding(b management.Binding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b.Arguments == nil {
+		b.Arguments = map[string]any{}
+	}
+	if b.DestinationType == "" {
+		b.DestinationType = "queue"
+	}
+	if b.PropertiesKey == "" {
+		b.PropertiesKey = b.RoutingKey
+	}
+	v := s.vhost(b.Vhost)
+	v.bindings = append(v.bindings, b)
+}
+
+// AddConsumer adds c and bumps the consumer count of its queue.
+func (s *Server) AddConsumer(c management.Consumer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.vhost(c.Queue.Vhost)
+	v.consumers = append(v.consumers, c)
+	for i := range v.queues {
+		if v.queues[i].Name == c.Queue.Name {
+			v.queues[i].Consumers++
+		}
+	}
+}
+
+func (s *Server) AddConnection(c management.Connection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.vhost(c.Vhost)
+	v.connections = append(v.connections, c)
+}
+
+func (s *Server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != Username || pass != Password {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{
+				"error":  "not_authorized",
+				"reason": "Login failed",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// route dispatches on the escaped path, since vhost names such as "/" are
+// sent as %2F and must stay a single segment.
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	var segs []string
+	for _, seg := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		u, err := url.PathUnescape(seg)
+		if err != nil {
+			notFound(w)
+			return
+		}
+		segs = append(segs, u)
+	}
+	if r.Method != http.MethodGet || len(segs) < 2 || segs[0] != "api" {
+		notFound(w)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case len(segs) == 2 && segs[1] == "overview":
+		writeJSON(w, http.StatusOK, s.currentOverview())
+	case len(segs) == 2 && segs[1] == "nodes":
+		writeJSON(w, http.StatusOK, s.nodes)
+	case len(segs) == 2 && segs[1] == "vhosts":
+		var out []management.Vhost
+		for _, name := range s.order {
+			out = append(out, s.vhosts[name].vhostInfo())
+		}
+		writeJSON(w, http.StatusOK, out)
+	case len(segs) == 2:
+		s.list(w, segs[1], s.order)
+	case len(segs) == 3:
+		if _, ok := s.lookup(w, segs[2]); ok {
+			s.list(w, segs[1], []string{segs[2]})
+		}
+	case len(segs) == 4 && segs[1] == "vhosts" && segs[3] == "connections":
+		if v, ok := s.lookup(w, segs[2]); ok {
+			writeJSON(w, http.StatusOK, nonNil(v.connections))
+		}
+	case len(segs) == 4 && segs[1] == "exchanges":
+		if v, ok := s.lookup(w, segs[2]); ok {
+			for _, e := range v.exchanges {
+				if e.Name == segs[3] {
+					writeJSON(w, http.StatusOK, e)
+					return
+				}
+			}
+			notFound(w)
+		}
+	case len(segs) == 4 && segs[1] == "queues":
+		if v, ok := s.lookup(w, segs[2]); ok {
+			for _, q := range v.queues {
+				if q.Name == segs[3] {
+					writeJSON(w, http.StatusOK, q)
+					return
+				}
+			}
+			notFound(w)
+		}
+	case len(segs) == 5 && segs[1] == "queues" && segs[4] == "bindings":
+		if v, ok := s.lookup(w, segs[2]); ok {
+			out := []management.Binding{}
+			for _, b := range v.bindings {
+				if b.DestinationType == "queue" && b.Destination == segs[3] {
+					out = append(out, b)
+				}
+			}
+			writeJSON(w, http.StatusOK, out)
+		}
+	default:
+		notFound(w)
+	}
+}
+
+// list writes every object of kind across the given vhosts.
+func (s *Server) list(w http.ResponseWriter, kind string, vhosts []string) {
+	var out any
+	switch kind {
+	case "exchanges":
+		all := []management.Exchange{}
+		for _, name := range vhosts {
+			all = append(all, s.vhosts[name].exchanges...)
+		}
+		out = all
+	case "queues":
+		all := []management.Queue{}
+		for _, name := range vhosts {
+			all = append(all, s.vhosts[name].queues...)
+		}
+		out = all
+	case "bindings":
+		all := []management.Binding{}
+		for _, name := range vhosts {
+			all = append(all, s.vhosts[name].bindings...)
+		}
+		out = all
+	case "consumers":
+		all := []management.Consumer{}
+		for _, name := range vhosts {
+			all = append(all, s.vhosts[name].consumers...)
+		}
+		out = all
+	case "connections":
+		all := []management.Connection{}
+		for _, name := range vhosts {
+			all = append(all, s.vhosts[name].connections...)
+		}
+		out = all
+	default:
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) currentOverview() management.Overview {
+	o := s.overview
+	o.ObjectTotals = management.ObjectTotals{}
+	o.QueueTotals = management.QueueTotals{}
+	for _, v := range s.vhosts {
+		o.ObjectTotals.Exchanges += len(v.exchanges)
+		o.ObjectTotals.Queues += len(v.queues)
+		o.ObjectTotals.Consumers += len(v.consumers)
+		o.ObjectTotals.Connections += len(v.connections)
+		for _, c := range v.connections {
+			o.ObjectTotals.Channels += c.Channels
+		}
+		info := v.vhostInfo()
+		o.QueueTotals.Messages += info.Messages
+		o.QueueTotals.MessagesReady += info.MessagesReady
+		o.QueueTotals.MessagesUnacknowledged += info.MessagesUnacknowledged
+	}
+	return o
+}
+
+func (v *vhost) vhostInfo() management.Vhost {
+	info := v.info
+	for _, q := range v.queues {
+		info.Messages += q.Messages
+		info.MessagesReady += q.MessagesReady
+		info.MessagesUnacknowledged += q.MessagesUnacknowledged
+	}
+	return info
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func (s *Server) lookup(w http.ResponseWriter, name string) (*vhost, bool) {
+	v, ok := s.vhosts[name]
+	if !ok {
+		notFound(w)
+	}
+	return v, ok
+}
+
+func notFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, map[string]string{
+		"error":  "Object Not Found",
+		"reason": "Not Found",
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,52 @@
+package management
+
+import "context"
+
+// listPath returns /api/<kind>/<vhost>, or /api/<kind> across all vhosts
+// when vhost is empty.
+func listPath(kind, vhost string) string {
+	if vhost == "" {
+		return "/api/" + kind
+	}
+	return "/api/" + kind + "/" + vhostPath(vhost)
+}
+
+// Exchanges lists the exchanges in vhost, or in every vhost if it is empty.
+func (c *Client) Exchanges(ctx context.Context, vhost string) ([]Exchange, error) {
+	var out []Exchange
+	err := c.get(ctx, listPath("exchanges", vhost), &out)
+	return out, err
+}
+
+func (c *Client) Exchange(ctx context.Context, vhost, name string) (Exchange, error) {
+	var out Exchange
+	err := c.get(ctx, "/api/exchanges/"+vhostPath(vhost)+"/"+vhostPath(name), &out)
+	return out, err
+}
+
+// Queues lists the queues in vhost, or in every vhost if it is empty.
+func (c *Client) Queues(ctx context.Context, vhost string) ([]Queue, error) {
+	var out []Queue
+	err := c.get(ctx, listPath("queues", vhost), &out)
+	return out, err
+}
+
+func (c *Client) Queue(ctx context.Context, vhost, name string) (Queue, error) {
+	var out Queue
+	err := c.get(ctx, "/api/queues/"+vhostPath(vhost)+"/"+vhostPath(name), &out)
+	return out, err
+}
+
+// Bindings lists the bindings in vhost, or in every vhost if it is empty.
+func (c *Client) Bindings(ctx context.Context, vhost string) ([]Binding, error) {
+	var out []Binding
+	err := c.get(ctx, listPath("bindings", vhost), &out)
+	return out, err
+}
+
+// QueueBindings lists the bindings whose destination is the named queue.
+func (c *Client) QueueBindings(ctx context.Context, vhost, name string) ([]Binding, error) {
+	var out []Binding
+	err := c.get(ctx, "/api/queues/"+vhostPath(vhost)+"/"+vhostPath(name)+"/bindings", &out)
+	return out, err
+}
@@ -0,0 +1,165 @@
+package management
+
+// Rate is a counter's rate of change as reported in *_details fields.
+type Rate struct {
+	Rate float64 `json:"rate"`
+}
+
+type MessageStats struct {
+	Publish           int64 `json:"publish"`
+	PublishDetails    Rate  `json:"publish_details"`
+	Deliver           int64 `json:"deliver"`
+	DeliverDetails    Rate  `json:"deliver_details"`
+	DeliverGet        int64 `json:"deliver_get"`
+	DeliverGetDetails Rate  `json:"deliver_get_details"`
+	Ack               int64 `json:"ack"`
+	AckDetails        Rate  `json:"ack_details"`
+	Redeliver         int64 `json:"redeliver"`
+	RedeliverDetails  Rate  `json:"redeliver_details"`
+}
+
+type ObjectTotals struct {
+	Channels    int `json:"channels"`
+	Connections int `json:"connections"`
+	Consumers   int `json:"consumers"`
+	Exchanges   int `json:"exchanges"`
+	Queues      int `json:"queues"`
+}
+
+type QueueTotals struct {
+	Messages               int64 `json:"messages"`
+	MessagesReady          int64 `json:"messages_ready"`
+	MessagesUnacknowledged int64 `json:"messages_unacknowledged"`
+}
+
+type Overview struct {
+	ClusterName       string       `json:"cluster_name"`
+	Node              string       `json:"node"`
+	ManagementVersion string       `json:"management_version"`
+	RabbitMQVersion   string       `json:"rabbitmq_version"`
+	ErlangVersion     string       `json:"erlang_version"`
+	ObjectTotals      ObjectTotals `json:"object_totals"`
+	QueueTotals       QueueTotals  `json:"queue_totals"`
+	MessageStats      MessageStats `json:"message_stats"`
+}
+
+type Node struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Running       bool   `json:"running"`
+	Uptime        int64  `json:"uptime"`
+	MemUsed       int64  `json:"mem_used"`
+	MemLimit      int64  `json:"mem_limit"`
+	MemAlarm      bool   `json:"mem_alarm"`
+	DiskFree      int64  `json:"disk_free"`
+	DiskFreeLimit int64  `json:"disk_free_limit"`
+	DiskFreeAlarm bool   `json:"disk_free_alarm"`
+	FDUsed        int64  `json:"fd_used"`
+	FDTotal       int64  `json:"fd_total"`
+	SocketsUsed   int64  `json:"sockets_used"`
+	SocketsTotal  int64  `json:"sockets_total"`
+	ProcUsed      int64  `json:"proc_used"`
+	ProcTotal     int64  `json:"proc_total"`
+}
+
+type Vhost struct {
+	Name                   string `json:"name"`
+	Description            string `json:"description"`
+	Tracing                bool   `json:"tracing"`
+	Messages               int64  `json:"messages"`
+	MessagesReady          int64  `json:"messages_ready"`
+	MessagesUnacknowledged int64  `json:"messages_unacknowledged"`
+}
+
+type Exchange struct {
+	Name         string         `json:"name"`
+	Vhost        string         `json:"vhost"`
+	Type         string         `json:"type"`
+	Durable      bool           `json:"durable"`
+	AutoDelete   bool           `json:"auto_delete"`
+	Internal     bool           `json:"internal"`
+	Arguments    map[string]any `json:"arguments"`
+	MessageStats MessageStats   `json:"message_stats"`
+}
+
+type Queue struct {
+	Name       string         `json:"name"`
+	Vhost      string         `json:"vhost"`
+	Type       string         `json:"type"`
+	Durable    bool           `json:"durable"`
+	AutoDelete bool           `json:"auto_delete"`
+	Exclusive  bool           `json:"exclusive"`
+	Arguments  map[string]any `json:"arguments"`
+	Node       string         `json:"node"`
+	State      string         `json:"state"`
+	Policy     string         `json:"policy"`
+	Leader     string         `json:"leader"`
+	Members    []string       `json:"members"`
+
+	Messages                      int64        `json:"messages"`
+	MessagesDetails               Rate         `json:"messages_details"`
+	MessagesReady                 int64        `json:"messages_ready"`
+	MessagesReadyDetails          Rate         `json:"messages_ready_details"`
+	MessagesUnacknowledged        int64        `json:"messages_unacknowledged"`
+	MessagesUnacknowledgedDetails Rate         `json:"messages_unacknowledged_details"`
+	MessageBytes                  int64        `json:"message_bytes"`
+	Memory                        int64        `json:"memory"`
+	Consumers                     int          `json:"consumers"`
+	ConsumerUtilisation           float64      `json:"consumer_utilisation"`
+	MessageStats                  MessageStats `json:"message_stats"`
+	IdleSince                     string       `json:"idle_since"`
+}
+
+type Binding struct {
+	Source          string         `json:"source"`
+	Vhost           string         `json:"vhost"`
+	Destination     string         `json:"destination"`
+	DestinationType string         `json:"destination_type"`
+	RoutingKey      string         `json:"routing_key"`
+	Arguments       map[string]any `json:"arguments"`
+	PropertiesKey   string         `json:"properties_key"`
+}
+
+type QueueRef struct {
+	Name  string `json:"name"`
+	Vhost string `json:"vhost"`
+}
+
+type ChannelDetails struct {
+	Name           string `json:"name"`
+	Number         int    `json:"number"`
+	ConnectionName string `json:"connection_name"`
+	PeerHost       string `json:"peer_host"`
+	PeerPort       int    `json:"peer_port"`
+	User           string `json:"user"`
+}
+
+type Consumer struct {
+	ConsumerTag    string         `json:"consumer_tag"`
+	Queue          QueueRef       `json:"queue"`
+	ChannelDetails ChannelDetails `json:"channel_details"`
+	AckRequired    bool           `json:"ack_required"`
+	Exclusive      bool           `json:"exclusive"`
+	PrefetchCount  int            `json:"prefetch_count"`
+	Active         bool           `json:"active"`
+	ActivityStatus string         `json:"activity_status"`
+	Arguments      map[string]any `json:"arguments"`
+}
+
+type Connection struct {
+	Name             string         `json:"name"`
+	Vhost            string         `json:"vhost"`
+	User             string         `json:"user"`
+	Node             string         `json:"node"`
+	State            string         `json:"state"`
+	Protocol         string         `json:"protocol"`
+	AuthMechanism    string         `json:"auth_mechanism"`
+	PeerHost         string         `json:"peer_host"`
+	PeerPort         int            `json:"peer_port"`
+	SSL              bool           `json:"ssl"`
+	Channels         int            `json:"channels"`
+	ConnectedAt      int64          `json:"connected_at"`
+	RecvOct          int64          `json:"recv_oct"`
+	SendOct          int64          `json:"send_oct"`
+	ClientProperties map[string]any `json:"client_properties"`
+}
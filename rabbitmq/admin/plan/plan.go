@@ -0,0 +1,285 @@
+// Package plan compares a desired topology with what exists on a broker and
+// reports the changes needed to reconcile them.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"admin/management"
+	"publisher/rabbitmq"
+)
+
+type Action string
+
+const (
+	CREATE Action = "create"
+	CHANGE Action = "change"
+	DELETE Action = "delete"
+)
+
+type Kind string
+
+const (
+	EXCHANGE Kind = "exchange"
+	QUEUE    Kind = "queue"
+	BINDING  Kind = "binding"
+)
+
+type Change struct {
+	Action  Action   `json:"action"`
+	Kind    Kind     `json:"kind"`
+	Name    string   `json:"name"`
+	Details []string `json:"details,omitempty"`
+}
+
+type Plan struct {
+	Changes []Change `json:"changes"`
+	// Notes explain what could not be compared.
+	Notes []string `json:"notes,omitempty"`
+}
+
+type Options struct {
+	// Prune reports exchanges, queues and bindings that exist on the broker
+	// but not in the desired topology for deletion. Without it only
+	// bindings on desired exchanges are considered.
+	Prune bool
+}
+
+// Diff returns the changes needed to turn current into desired.
+func Diff(desired rabbitmq.Topology, current State, opts Options) Plan {
+	var p Plan
+
+	wantEx := map[string]bool{}
+	for _, e := range desired.Exchanges {
+		wantEx[e.Name] = true
+		have, ok := current.Exchanges[e.Name]
+		if !ok {
+			p.add(CREATE, EXCHANGE, e.Name, fmt.Sprintf("type %v", e.Type))
+			continue
+		}
+		if current.Partial {
+			continue
+		}
+		var d []string
+		d = appendField(d, "type", have.Type, string(e.Type))
+		d = appendField(d, "durable", have.Durable, true)
+		d = appendField(d, "auto_delete", have.AutoDelete, false)
+		d = appendField(d, "internal", have.Internal, false)
+		d = append(d, argsDiff(have.Arguments, nil)...)
+		if len(d) > 0 {
+			d = append(d, "exchange must be deleted and re-declared to apply")
+			p.add(CHANGE, EXCHANGE, e.Name, d...)
+		}
+	}
+
+	wantQ := map[string]bool{}
+	for _, q := range desired.Queues {
+		wantQ[q.Name] = true
+		have, ok := current.Queues[q.Name]
+		if !ok {
+			p.add(CREATE, QUEUE, q.Name, argsDiff(nil, q.Args)...)
+			continue
+		}
+		if current.Partial {
+			continue
+		}
+		var d []string
+		d = appendField(d, "durable", have.Durable, true)
+		d = appendField(d, "auto_delete", have.AutoDelete, false)
+		d = appendField(d, "exclusive", have.Exclusive, false)
+		d = append(d, argsDiff(have.Arguments, q.Args)...)
+		if len(d) > 0 {
+			d = append(d, "queue must be deleted and re-declared to apply, its messages are lost")
+			p.add(CHANGE, QUEUE, q.Name, d...)
+		}
+	}
+
+	if current.Partial {
+		p.Notes = append(p.Notes, "state read with passive declares: arguments and bindings were not compared")
+		for _, b := range desired.Bindings {
+			_, exOK := current.Exchanges[b.Exchange]
+			_, qOK := current.Queues[b.Queue]
+			if !exOK || !qOK {
+				p.add(CREATE, BINDING, bindingName(b.Exchange, b.Queue, b.RoutingKey), argsDiff(nil, b.Args)...)
+			}
+		}
+		p.sort()
+		return p
+	}
+
+	have := map[string]management.Binding{}
+	for _, b := range current.Bindings {
+		if b.Source == "" || b.DestinationType != "queue" {
+			// default exchange bindings are implicit
+			continue
+		}
+		have[bindingKey(b.Source, b.Destination, b.RoutingKey, b.Arguments)] = b
+	}
+	want := map[string]bool{}
+	for _, b := range desired.Bindings {
+		k := bindingKey(b.Exchange, b.Queue, b.RoutingKey, b.Args)
+		want[k] = true
+		if _, ok := have[k]; !ok {
+			p.add(CREATE, BINDING, bindingName(b.Exchange, b.Queue, b.RoutingKey), argsDiff(nil, b.Args)...)
+		}
+	}
+	for k, b := range have {
+		if want[k] || (!opts.Prune && !wantEx[b.Source]) {
+			continue
+		}
+		p.add(DELETE, BINDING, bindingName(b.Source, b.Destination, b.RoutingKey), argsDiff(b.Arguments, nil)...)
+	}
+
+	if opts.Prune {
+		for name, e := range current.Exchanges {
+			if wantEx[name] || name == "" || strings.HasPrefix(name, "amq.") {
+				continue
+			}
+			p.add(DELETE, EXCHANGE, name, fmt.Sprintf("type %v", e.Type))
+		}
+		for name, q := range current.Queues {
+			if wantQ[name] || q.Exclusive || strings.HasPrefix(name, "amq.gen-") {
+				continue
+			}
+			p.add(DELETE, QUEUE, name)
+		}
+	}
+
+	p.sort()
+	return p
+}
+
+func (p *Plan) add(a Action, k Kind, name string, details ...string) {
+	p.Changes = append(p.Changes, Change{Action: a, Kind: k, Name: name, Details: details})
+}
+
+var kindOrder = map[Kind]int{EXCHANGE: 0, QUEUE: 1, BINDING: 2}
+
+func (p *Plan) sort() {
+	sort.SliceStable(p.Changes, func(i, j int) bool {
+		a, b := p.Changes[i], p.Changes[j]
+		if kindOrder[a.Kind] != kindOrder[b.Kind] {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		return a.Name < b.Name
+	})
+}
+
+// Count returns how many changes of each action the plan holds.
+func (p Plan) Count() (create, change, del int) {
+	for _, c := range p.Changes {
+		switch c.Action {
+		case CREATE:
+			create++
+		case CHANGE:
+			change++
+		case DELETE:
+			del++
+		}
+	}
+	return
+}
+
+var symbols = map[Action]string{CREATE: "+", CHANGE: "~", DELETE: "-"}
+
+func (p Plan) Write(w io.Writer) error {
+	for _, c := range p.Changes {
+		if _, err := fmt.Fprintf(w, "%v %v %v\n", symbols[c.Action], c.Kind, c.Name); err != nil {
+			return err
+		}
+		for _, d := range c.Details {
+			if _, err := fmt.Fprintf(w, "    %v\n", d); err != nil {
+				return err
+			}
+		}
+	}
+	for _, n := range p.Notes {
+		if _, err := fmt.Fprintf(w, "note: %v\n", n); err != nil {
+			return err
+		}
+	}
+	if len(p.Changes) == 0 {
+		_, err := fmt.Fprintln(w, "No changes. The broker matches the desired topology.")
+		return err
+	}
+	create, change, del := p.Count()
+	_, err := fmt.Fprintf(w, "\nPlan: %v to create, %v to change, %v to delete.\n", create, change, del)
+	return err
+}
+
+func bindingName(exchange, queue, routingKey string) string {
+	return fmt.Sprintf("%v -> %v [%v]", exchange, queue, routingKey)
+}
+
+func bindingKey(exchange, queue, routingKey string, args map[string]any) string {
+	raw, _ := json.Marshal(normalize(args))
+	return exchange + "\x00" + queue + "\x00" + routingKey + "\x00" + string(raw)
+}
+
+// normalize converts args to the shape the management API returns them in,
+// so that values declared as Go ints compare equal to decoded JSON numbers.
+func normalize(args map[string]any) map[string]any {
+	out := map[string]any{}
+	if len(args) == 0 {
+		return out
+	}
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return args
+	}
+	json.Unmarshal(raw, &out)
+	return out
+}
+
+func appendField(d []string, name string, have, want any) []string {
+	if reflect.DeepEqual(have, want) {
+		return d
+	}
+	return append(d, fmt.Sprintf("~ %v: %v -> %v", name, have, want))
+}
+
+// argsDiff describes how to get from the have arguments to want.
+func argsDiff(have, want map[string]any) []string {
+	h, w := normalize(have), normalize(want)
+
+	keys := map[string]bool{}
+	for k := range h {
+		keys[k] = true
+	}
+	for k := range w {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var d []string
+	for _, k := range sorted {
+		hv, inH := h[k]
+		wv, inW := w[k]
+		switch {
+		case !inH:
+			d = append(d, fmt.Sprintf("+ %v = %v", k, quote(wv)))
+		case !inW:
+			d = append(d, fmt.Sprintf("- %v = %v", k, quote(hv)))
+		case !reflect.DeepEqual(hv, wv):
+			d = append(d, fmt.Sprintf("~ %v: %v -> %v", k, quote(hv), quote(wv)))
+		}
+	}
+	return d
+}
+
+func quote(v any) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(raw)
+}
@@ -0,0 +1,173 @@
+package plan
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"admin/management"
+	"admin/management/mgmttest"
+	"publisher/rabbitmq"
+	"publisher/rabbitmq/publisher"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// declare adds t to the "/" vhost of srv the way the broker would after
+// Topology.Declare.
+func declare(srv *mgmttest.Server, t rabbitmq.Topology) {
+	for _, e := range t.Exchanges {
+		srv.AddExchange(management.Exchange{Name: e.Name, Vhost: "/", Type: string(e.Type), Durable: true})
+	}
+	for _, q := range t.Queues {
+		srv.AddQueue(management.Queue{Name: q.Name, Vhost: "/", Durable: true, Arguments: q.Args})
+	}
+	for _, b := range t.Bindings {
+		srv.AddBinding(management.Binding{Source: b.Exchange, Vhost: "/", Destination: b.Queue, RoutingKey: b.RoutingKey, Arguments: b.Args})
+	}
+}
+
+func state(t *testing.T, srv *mgmttest.Server) State {
+	t.Helper()
+	s, err := FromManagement(context.Background(), srv.Client(), "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+type change struct {
+	action Action
+	kind   Kind
+	name   string
+}
+
+func changes(p Plan) []change {
+	var out []change
+	for _, c := range p.Changes {
+		out = append(out, change{c.Action, c.Kind, c.Name})
+	}
+	return out
+}
+
+func TestDiffEmptyBroker(t *testing.T) {
+	srv := mgmttest.NewServer()
+	defer srv.Close()
+
+	desired := publisher.Topology()
+	p := Diff(desired, state(t, srv), Options{})
+	create, change, del := p.Count()
+	want := len(desired.Exchanges) + len(desired.Queues) + len(desired.Bindings)
+	if create != want || change != 0 || del != 0 {
+		t.Fatalf("plan %v/%v/%v, want %v creates", create, change, del, want)
+	}
+	if c := p.Changes[0]; c.Kind != EXCHANGE {
+		t.Fatalf("first change is a %v, exchanges come first", c.Kind)
+	}
+}
+
+func TestDiffDeclaredTopologyHasNoChanges(t *testing.T) {
+	srv := mgmttest.NewServer()
+	defer srv.Close()
+
+	desired := publisher.Topology()
+	declare(srv, desired)
+	// bindings of other exchanges and default exchange bindings are left
+	// alone without Prune
+	srv.AddQueue(management.Queue{Name: "other", Vhost: "/", Durable: true})
+	srv.AddBinding(management.Binding{Source: "amq.topic", Vhost: "/", Destination: "other", RoutingKey: "#"})
+
+	p := Diff(desired, state(t, srv), Options{})
+	if len(p.Changes) != 0 {
+		t.Fatalf("changes %+v, want none", p.Changes)
+	}
+	var out strings.Builder
+	p.Write(&out)
+	if !strings.Contains(out.String(), "No changes") {
+		t.Fatalf("output %q", out.String())
+	}
+}
+
+func TestDiffChanges(t *testing.T) {
+	srv := mgmttest.NewServer()
+	defer srv.Close()
+
+	desired := rabbitmq.Topology{
+		Exchanges: []rabbitmq.Exchange{{Name: "orders", Type: "topic"}},
+		Queues: []rabbitmq.Queue{
+			{Name: "created", Args: amqp.Table{"x-queue-type": "quorum", "x-max-length": 100}},
+			{Name: "shipped"},
+		},
+		Bindings: []rabbitmq.Binding{
+			{Exchange: "orders", Queue: "created", RoutingKey: "order.created"},
+			{Exchange: "orders", Queue: "shipped", RoutingKey: "order.shipped"},
+		},
+	}
+	srv.AddExchange(management.Exchange{Name: "orders", Vhost: "/", Type: "direct", Durable: true})
+	srv.AddExchange(management.Exchange{Name: "legacy", Vhost: "/", Type: "fanout", Durable: true})
+	srv.AddQueue(management.Queue{Name: "created", Vhost: "/", Durable: true, Arguments: map[string]any{"x-queue-type": "quorum", "x-max-length": 50}})
+	srv.AddQueue(management.Queue{Name: "stale", Vhost: "/", Durable: true})
+	srv.AddBinding(management.Binding{Source: "orders", Vhost: "/", Destination: "created", RoutingKey: "order.created"})
+	srv.AddBinding(management.Binding{Source: "orders", Vhost: "/", Destination: "stale", RoutingKey: "order.#"})
+
+	p := Diff(desired, state(t, srv), Options{})
+	want := []change{
+		{CHANGE, EXCHANGE, "orders"},
+		{CHANGE, QUEUE, "created"},
+		{CREATE, QUEUE, "shipped"},
+		{CREATE, BINDING, "orders -> shipped [order.shipped]"},
+		{DELETE, BINDING, "orders -> stale [order.#]"},
+	}
+	if got := changes(p); !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes\n%+v\nwant\n%+v", got, want)
+	}
+	if d := p.Changes[0].Details; d[0] != "~ type: direct -> topic" {
+		t.Fatalf("exchange details %q", d)
+	}
+	if d := p.Changes[1].Details; d[0] != "~ x-max-length: 50 -> 100" {
+		t.Fatalf("queue details %q", d)
+	}
+
+	p = Diff(desired, state(t, srv), Options{Prune: true})
+	want = []change{
+		{DELETE, EXCHANGE, "legacy"},
+		{CHANGE, EXCHANGE, "orders"},
+		{CHANGE, QUEUE, "created"},
+		{CREATE, QUEUE, "shipped"},
+		{DELETE, QUEUE, "stale"},
+		{CREATE, BINDING, "orders -> shipped [order.shipped]"},
+		{DELETE, BINDING, "orders -> stale [order.#]"},
+	}
+	if got := changes(p); !reflect.DeepEqual(got, want) {
+		t.Fatalf("pruned changes\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestDiffPartialState(t *testing.T) {
+	desired := rabbitmq.Topology{
+		Exchanges: []rabbitmq.Exchange{{Name: "orders", Type: "topic"}},
+		Queues:    []rabbitmq.Queue{{Name: "created"}, {Name: "shipped"}},
+		Bindings: []rabbitmq.Binding{
+			{Exchange: "orders", Queue: "created", RoutingKey: "order.created"},
+			{Exchange: "orders", Queue: "shipped", RoutingKey: "order.shipped"},
+		},
+	}
+	current := State{
+		Exchanges: map[string]management.Exchange{"orders": {Name: "orders"}},
+		Queues:    map[string]management.Queue{"created": {Name: "created"}},
+		Partial:   true,
+	}
+
+	p := Diff(desired, current, Options{})
+	want := []change{
+		{CREATE, QUEUE, "shipped"},
+		{CREATE, BINDING, "orders -> shipped [order.shipped]"},
+	}
+	if got := changes(p); !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes %+v, want %+v", got, want)
+	}
+	if len(p.Notes) != 1 {
+		t.Fatalf("notes %q, want one about the partial state", p.Notes)
+	}
+}
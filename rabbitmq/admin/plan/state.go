@@ -0,0 +1,103 @@
+package plan
+
+import (
+	"context"
+	"errors"
+
+	"admin/management"
+	"publisher/rabbitmq"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// State is what currently exists on the broker.
+type State struct {
+	Exchanges map[string]management.Exchange
+	Queues    map[string]management.Queue
+	Bindings  []management.Binding
+	// Partial is set when the state came from passive declares, which only
+	// reveal whether exchanges and queues exist. Arguments and bindings are
+	// not compared for partial states.
+	Partial bool
+}
+
+// FromManagement reads the state of vhost through the management API.
+func FromManagement(ctx context.Context, c *management.Client, vhost string) (State, error) {
+	exchanges, err := c.Exchanges(ctx, vhost)
+	if err != nil {
+		return State{}, err
+	}
+	queues, err := c.Queues(ctx, vhost)
+	if err != nil {
+		return State{}, err
+	}
+	bindings, err := c.Bindings(ctx, vhost)
+	if err != nil {
+		return State{}, err
+	}
+
+	s := State{
+		Exchanges: map[string]management.Exchange{},
+		Queues:    map[string]management.Queue{},
+		Bindings:  bindings,
+	}
+	for _, e := range exchanges {
+		s.Exchanges[e.Name] = e
+	}
+	for _, q := range queues {
+		s.Queues[q.Name] = q
+	}
+	return s, nil
+}
+
+// FromAMQP checks which of the desired exchanges and queues exist using
+// passive declares on conn.
+func FromAMQP(conn *amqp.Connection, desired rabbitmq.Topology) (State, error) {
+	s := State{
+		Exchanges: map[string]management.Exchange{},
+		Queues:    map[string]management.Queue{},
+		Partial:   true,
+	}
+
+	// a failed passive declare closes the channel, so every check gets its
+	// own
+	check := func(declare func(ch *amqp.Channel) error) (bool, error) {
+		ch, err := conn.Channel()
+		if err != nil {
+			return false, err
+		}
+		defer ch.Close()
+
+		err = declare(ch)
+		var aerr *amqp.Error
+		if errors.As(err, &aerr) && aerr.Code == amqp.NotFound {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	for _, e := range desired.Exchanges {
+		ok, err := check(func(ch *amqp.Channel) error {
+			return ch.ExchangeDeclarePassive(e.Name, string(e.Type), true, false, false, false, nil)
+		})
+		if err != nil {
+			return State{}, err
+		}
+		if ok {
+			s.Exchanges[e.Name] = management.Exchange{Name: e.Name}
+		}
+	}
+	for _, q := range desired.Queues {
+		ok, err := check(func(ch *amqp.Channel) error {
+			_, err := ch.QueueDeclarePassive(q.Name, true, false, false, false, nil)
+			return err
+		})
+		if err != nil {
+			return State{}, err
+		}
+		if ok {
+			s.Queues[q.Name] = management.Queue{Name: q.Name}
+		}
+	}
+	return s, nil
+}
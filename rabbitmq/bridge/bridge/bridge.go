@@ -0,0 +1,293 @@
+// Package bridge moves messages between RabbitMQ queues and Kafka topics.
+// The source only learns a message was taken once the destination has it:
+// deliveries are acked after Kafka acknowledged the records, and Kafka
+// offsets are committed after RabbitMQ confirmed the publishings.
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"producer/kafka"
+	"publisher/logger"
+	"publisher/rabbitmq"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	defaultPrefetch = 100
+	defaultWorkers  = 8
+	// flushInterval bounds how long deliveries wait for a batch to fill
+	// before they are produced to Kafka.
+	flushInterval = 50 * time.Millisecond
+	// dropLogInterval is how often a route at most logs the messages it
+	// keeps from looping.
+	dropLogInterval = time.Minute
+)
+
+// ToKafka bridges a queue to a topic.
+type ToKafka struct {
+	Queue string `json:"queue"`
+	Topic string `json:"topic"`
+	// Key picks the record key, the routing key by default.
+	Key     KeySource     `json:"key"`
+	Headers HeaderMapping `json:"headers"`
+	// Prefetch is how many deliveries are produced to Kafka at once.
+	Prefetch int `json:"prefetch,omitempty"`
+}
+
+// ToRabbitMQ bridges a topic to an exchange.
+type ToRabbitMQ struct {
+	Topic    string `json:"topic"`
+	Group    string `json:"group"`
+	Exchange string `json:"exchange"`
+	// RoutingKey picks the routing key, the record key by default.
+	RoutingKey KeySource     `json:"routing_key"`
+	Headers    HeaderMapping `json:"headers"`
+	// Workers is how many keys are published in parallel; records of one
+	// key keep their order.
+	Workers int `json:"workers,omitempty"`
+}
+
+// Config lists the routes of a bridge and how to reach both brokers.
+type Config struct {
+	ToKafka    []ToKafka    `json:"to_kafka"`
+	ToRabbitMQ []ToRabbitMQ `json:"to_rabbitmq"`
+
+	// Rabbitmq is dialed once per route.
+	Rabbitmq rabbitmq.Rabbitmq `json:"-"`
+	// Kafka configures the producers and consumers; routes add the topic
+	// and group.
+	Kafka []kafka.Option `json:"-"`
+}
+
+func (c Config) Validate() error {
+	if len(c.ToKafka)+len(c.ToRabbitMQ) == 0 {
+		return errors.New("no routes configured")
+	}
+	for _, r := range c.ToKafka {
+		if r.Queue == "" || r.Topic == "" {
+			return fmt.Errorf("route to kafka %+v needs a queue and a topic", r)
+		}
+	}
+	for _, r := range c.ToRabbitMQ {
+		if r.Topic == "" || r.Group == "" {
+			return fmt.Errorf("route to rabbitmq %+v needs a topic and a group", r)
+		}
+	}
+	return nil
+}
+
+// Run runs every route until ctx is cancelled or one of them fails, and
+// returns the first failure.
+func Run(ctx context.Context, cfg Config, log *logger.Logger) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		rerr error
+	)
+	start := func(name string, run func(context.Context, *amqp.Connection) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := cfg.withConnection(ctx, run)
+			if err != nil && ctx.Err() == nil {
+				once.Do(func() {
+					rerr = fmt.Errorf("%v: %w", name, err)
+					cancel()
+				})
+			}
+		}()
+	}
+	for _, r := range cfg.ToKafka {
+		log.Info("bridging queue to topic", "queue", r.Queue, "topic", r.Topic, "key", r.Key.String())
+		start(fmt.Sprintf("queue %v -> topic %v", r.Queue, r.Topic), func(ctx context.Context, conn *amqp.Connection) error {
+			return r.run(ctx, conn, cfg.Kafka, log)
+		})
+	}
+	for _, r := range cfg.ToRabbitMQ {
+		log.Info("bridging topic to exchange", "topic", r.Topic, "group", r.Group, "exchange", r.Exchange, "routing_key", r.RoutingKey.String())
+		start(fmt.Sprintf("topic %v -> exchange %q", r.Topic, r.Exchange), func(ctx context.Context, conn *amqp.Connection) error {
+			return r.run(ctx, conn, cfg.Kafka, log)
+		})
+	}
+	wg.Wait()
+	return rerr
+}
+
+// withConnection dials RabbitMQ for one route. Routes get their own
+// connection so that a slow one doesn't hold up the others' frames.
+func (c Config) withConnection(ctx context.Context, run func(context.Context, *amqp.Connection) error) error {
+	r := c.Rabbitmq
+	if err := r.Init(); err != nil {
+		return err
+	}
+	defer r.Connection.Close()
+	return run(ctx, r.Connection)
+}
+
+// run consumes the queue and produces batches of deliveries, acking each
+// batch once Kafka acknowledged it. A failed batch is requeued.
+func (r ToKafka) run(ctx context.Context, conn *amqp.Connection, opts []kafka.Option, log *logger.Logger) error {
+	prefetch := r.Prefetch
+	if prefetch <= 0 {
+		prefetch = defaultPrefetch
+	}
+
+	p, err := kafka.NewProducer(append(opts,
+		kafka.WithTopic(r.Topic),
+		// batches are sent synchronously, don't wait for more to arrive
+		kafka.WithLinger(time.Millisecond),
+		kafka.WithBatchSize(prefetch),
+	)...)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	if err := ch.Qos(prefetch, 0, false); err != nil {
+		return err
+	}
+	deliveries, err := ch.ConsumeWithContext(ctx, r.Queue, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	var (
+		batch   []kafka.Message
+		pending int
+		last    uint64
+	)
+	drop := dropLogger(log, "queue", r.Queue, "topic", r.Topic)
+	flush := func() error {
+		if pending == 0 {
+			return nil
+		}
+		if err := p.Send(ctx, batch...); err != nil {
+			return errors.Join(err, ch.Nack(last, true, true))
+		}
+		batch, pending = batch[:0], 0
+		return ch.Ack(last, true)
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return errors.New("rabbitmq closed the delivery channel")
+			}
+			last = d.DeliveryTag
+			pending++
+			// acked along with the batch but not bridged back
+			if cameFromTopic(d, r.Topic) {
+				drop("delivery_tag", d.DeliveryTag, "message_id", d.MessageId)
+			} else {
+				batch = append(batch, toKafka(d, r.Key, r.Headers))
+			}
+			if pending >= prefetch {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		case <-ticker.C:
+			if err := flush(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			// unacked deliveries are requeued when the channel closes
+			return nil
+		}
+	}
+}
+
+// run consumes the topic and publishes every record with a publisher
+// confirm. Records are committed only once RabbitMQ confirmed them.
+func (r ToRabbitMQ) run(ctx context.Context, conn *amqp.Connection, opts []kafka.Option, log *logger.Logger) error {
+	workers := r.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	if err := ch.Confirm(false); err != nil {
+		return err
+	}
+
+	c, err := kafka.NewConsumer(append(opts,
+		kafka.WithTopic(r.Topic),
+		kafka.WithGroup(r.Group),
+		kafka.WithKeyWorkers(workers),
+		kafka.WithCommitBatch(100),
+		kafka.WithCommitInterval(time.Second),
+	)...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	drop := dropLogger(log, "topic", r.Topic, "exchange", r.Exchange)
+	return c.Run(ctx, func(ctx context.Context, m kafka.Message) error {
+		if cameFromExchange(m, r.Exchange) {
+			drop("partition", m.Partition, "offset", m.Offset)
+			return nil
+		}
+		pub, routingKey := toAMQP(m, r.RoutingKey, r.Headers)
+		dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, r.Exchange, routingKey, false, false, pub)
+		if err != nil {
+			return err
+		}
+		ok, err := dc.WaitContext(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("rabbitmq nacked %v/%v/%v", m.Topic, m.Partition, m.Offset)
+		}
+		return nil
+	})
+}
+
+// dropLogger logs messages a route skips because they would return to the
+// topic or exchange they came from. They show the routes form a loop, so
+// they are logged at info level, but at most once per dropLogInterval with
+// the number dropped since and the fields of the latest one.
+func dropLogger(log *logger.Logger, route ...any) func(fields ...any) {
+	var (
+		mu      sync.Mutex
+		last    time.Time
+		dropped int
+	)
+	return func(fields ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped++
+		if now := time.Now(); now.Sub(last) >= dropLogInterval {
+			fields = append(append(append([]any(nil), route...), "dropped", dropped), fields...)
+			log.Info("not bridging messages back to where they came from", fields...)
+			last, dropped = now, 0
+		}
+	}
+}
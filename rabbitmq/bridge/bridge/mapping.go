@@ -0,0 +1,243 @@
+package bridge
+
+import (
+	"fmt"
+	"producer/kafka"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Headers linking AMQP properties to Kafka headers. Correlation ID and
+// type use the names of kafka.Envelope so that bridged events open as
+// envelopes.
+const (
+	headerContentType = "content-type"
+	headerMessageID   = "message-id"
+	// HeaderBridgedFrom marks messages the bridge wrote with where they
+	// came from: "kafka:TOPIC" on publishings and "rabbitmq:EXCHANGE" on
+	// records. A message is not bridged back into the topic or exchange it
+	// came from, which keeps a queue and a topic bridged both ways from
+	// looping, while paths such as topic A -> exchange E -> queue Q ->
+	// topic B are bridged as usual.
+	HeaderBridgedFrom = "x-bridged-from"
+
+	fromRabbitMQ = "rabbitmq"
+	fromKafka    = "kafka"
+)
+
+// bridgedFrom is the HeaderBridgedFrom value of messages that came from
+// broker through the topic or exchange name.
+func bridgedFrom(broker, name string) string {
+	return broker + ":" + name
+}
+
+// cameFromTopic reports whether delivery d was bridged out of topic, so
+// that bridging it into topic again would loop.
+func cameFromTopic(d amqp.Delivery, topic string) bool {
+	from, _ := d.Headers[HeaderBridgedFrom].(string)
+	return from == bridgedFrom(fromKafka, topic)
+}
+
+// cameFromExchange reports whether record m was bridged out of exchange,
+// so that bridging it into exchange again would loop.
+func cameFromExchange(m kafka.Message, exchange string) bool {
+	from, ok := m.Header(HeaderBridgedFrom)
+	return ok && string(from) == bridgedFrom(fromRabbitMQ, exchange)
+}
+
+// KeySource says where a bridged message's key comes from: towards Kafka
+// the key is the record key, towards RabbitMQ the routing key.
+//
+// It is written "key" for the source's own key, the routing key or the
+// record key; "header:NAME" for a header; "static:VALUE" for a fixed value;
+// or "none".
+type KeySource struct {
+	kind  string
+	value string
+}
+
+const (
+	keyOwn    = "key"
+	keyHeader = "header"
+	keyStatic = "static"
+	keyNone   = "none"
+)
+
+// SourceKey is the default KeySource: routing key to record key and back.
+var SourceKey = KeySource{kind: keyOwn}
+
+func ParseKeySource(s string) (KeySource, error) {
+	switch s {
+	case "", keyOwn:
+		return SourceKey, nil
+	case keyNone:
+		return KeySource{kind: keyNone}, nil
+	}
+	kind, value, ok := strings.Cut(s, ":")
+	if ok && (kind == keyHeader || kind == keyStatic) && (value != "" || kind == keyStatic) {
+		return KeySource{kind: kind, value: value}, nil
+	}
+	return KeySource{}, fmt.Errorf("bad key source %q, want key, header:NAME, static:VALUE or none", s)
+}
+
+func (k KeySource) String() string {
+	switch k.kind {
+	case "", keyOwn:
+		return keyOwn
+	case keyNone:
+		return keyNone
+	}
+	return k.kind + ":" + k.value
+}
+
+func (k KeySource) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *KeySource) UnmarshalText(b []byte) error {
+	parsed, err := ParseKeySource(string(b))
+	if err != nil {
+		return err
+	}
+	*k = parsed
+	return nil
+}
+
+// pick returns the key given the source's own key and a header lookup.
+func (k KeySource) pick(own string, header func(string) (string, bool)) string {
+	switch k.kind {
+	case "", keyOwn:
+		return own
+	case keyHeader:
+		v, _ := header(k.value)
+		return v
+	case keyStatic:
+		return k.value
+	}
+	return ""
+}
+
+// HeaderMapping selects and renames the headers copied to the other broker.
+type HeaderMapping struct {
+	// Include lists the headers to copy; every header is copied when it is
+	// empty.
+	Include []string `json:"include,omitempty"`
+	// Exclude lists headers that are never copied.
+	Exclude []string `json:"exclude,omitempty"`
+	// Rename maps source header names to destination names.
+	Rename map[string]string `json:"rename,omitempty"`
+}
+
+// target returns the destination name of header name and whether it is
+// copied at all.
+func (h HeaderMapping) target(name string) (string, bool) {
+	if name == HeaderBridgedFrom || slices.Contains(h.Exclude, name) {
+		return "", false
+	}
+	if len(h.Include) > 0 && !slices.Contains(h.Include, name) {
+		return "", false
+	}
+	if to, ok := h.Rename[name]; ok {
+		return to, true
+	}
+	return name, true
+}
+
+// toKafka builds the record for delivery d. Header values that are not
+// strings or bytes are formatted as text.
+func toKafka(d amqp.Delivery, key KeySource, headers HeaderMapping) kafka.Message {
+	lookup := func(name string) (string, bool) {
+		v, ok := d.Headers[name]
+		if !ok {
+			return "", false
+		}
+		return string(headerBytes(v)), true
+	}
+	m := kafka.Message{
+		Value: d.Body,
+		Time:  d.Timestamp,
+	}
+	if k := key.pick(d.RoutingKey, lookup); k != "" {
+		m.Key = []byte(k)
+	}
+
+	add := func(name, value string) {
+		if to, ok := headers.target(name); ok && value != "" {
+			m.Headers = append(m.Headers, kafka.Header{Key: to, Value: []byte(value)})
+		}
+	}
+	add(headerContentType, d.ContentType)
+	add(headerMessageID, d.MessageId)
+	add(kafka.HeaderCorrelationID, d.CorrelationId)
+	add(kafka.HeaderEventType, d.Type)
+	for _, name := range sortedNames(d.Headers) {
+		if to, ok := headers.target(name); ok {
+			m.Headers = append(m.Headers, kafka.Header{Key: to, Value: headerBytes(d.Headers[name])})
+		}
+	}
+	m.Headers = append(m.Headers, kafka.Header{Key: HeaderBridgedFrom, Value: []byte(bridgedFrom(fromRabbitMQ, d.Exchange))})
+	return m
+}
+
+// toAMQP builds the publishing and routing key for record m. Headers that
+// hold valid UTF-8 become strings, others stay bytes.
+func toAMQP(m kafka.Message, key KeySource, headers HeaderMapping) (amqp.Publishing, string) {
+	lookup := func(name string) (string, bool) {
+		v, ok := m.Header(name)
+		return string(v), ok
+	}
+	p := amqp.Publishing{
+		Body:         m.Value,
+		Timestamp:    m.Time,
+		DeliveryMode: amqp.Persistent,
+		Headers:      amqp.Table{HeaderBridgedFrom: bridgedFrom(fromKafka, m.Topic)},
+	}
+	for _, h := range m.Headers {
+		to, ok := headers.target(h.Key)
+		if !ok {
+			continue
+		}
+		switch h.Key {
+		case headerContentType:
+			p.ContentType = string(h.Value)
+		case headerMessageID:
+			p.MessageId = string(h.Value)
+		case kafka.HeaderCorrelationID:
+			p.CorrelationId = string(h.Value)
+		case kafka.HeaderEventType:
+			p.Type = string(h.Value)
+		default:
+			if utf8.Valid(h.Value) {
+				p.Headers[to] = string(h.Value)
+			} else {
+				p.Headers[to] = h.Value
+			}
+		}
+	}
+	return p, key.pick(string(m.Key), lookup)
+}
+
+func headerBytes(v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	case time.Time:
+		return []byte(v.UTC().Format(time.RFC3339))
+	}
+	return []byte(fmt.Sprint(v))
+}
+
+func sortedNames(t amqp.Table) []string {
+	names := make([]string, 0, len(t))
+	for k := range t {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	return names
+}
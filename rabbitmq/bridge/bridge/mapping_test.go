@@ -0,0 +1,284 @@
+package bridge
+
+import (
+	"producer/kafka"
+	"reflect"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestParseKeySource(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want KeySource
+		str  string
+		bad  bool
+	}{
+		{in: "", want: SourceKey, str: "key"},
+		{in: "key", want: SourceKey, str: "key"},
+		{in: "none", want: KeySource{kind: keyNone}, str: "none"},
+		{in: "header:tenant", want: KeySource{kind: keyHeader, value: "tenant"}, str: "header:tenant"},
+		{in: "static:orders", want: KeySource{kind: keyStatic, value: "orders"}, str: "static:orders"},
+		{in: "static:a:b", want: KeySource{kind: keyStatic, value: "a:b"}, str: "static:a:b"},
+		// an empty static key clears the key
+		{in: "static:", want: KeySource{kind: keyStatic}, str: "static:"},
+		{in: "header:", bad: true},
+		{in: "routing", bad: true},
+		{in: "value:x", bad: true},
+	} {
+		got, err := ParseKeySource(tt.in)
+		if tt.bad {
+			if err == nil {
+				t.Errorf("ParseKeySource(%q) = %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseKeySource(%q) = %#v, %v, want %#v", tt.in, got, err, tt.want)
+			continue
+		}
+		if got.String() != tt.str {
+			t.Errorf("ParseKeySource(%q).String() = %q, want %q", tt.in, got.String(), tt.str)
+		}
+		var back KeySource
+		if err := back.UnmarshalText([]byte(got.String())); err != nil || back != got {
+			t.Errorf("%q does not round trip: %#v, %v", got.String(), back, err)
+		}
+	}
+}
+
+func TestToKafka(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	d := amqp.Delivery{
+		Exchange:      "orders",
+		RoutingKey:    "eu.created",
+		ContentType:   "application/json",
+		MessageId:     "m-1",
+		CorrelationId: "c-1",
+		Type:          "OrderCreated",
+		Timestamp:     ts,
+		Body:          []byte(`{"id":1}`),
+		Headers: amqp.Table{
+			"tenant":          "acme",
+			"raw":             []byte{0xff},
+			"attempt":         int32(2),
+			"at":              ts,
+			HeaderBridgedFrom: "kafka:payments",
+		},
+	}
+	for _, tt := range []struct {
+		name    string
+		key     KeySource
+		headers HeaderMapping
+		wantKey []byte
+		want    []kafka.Header
+	}{
+		{
+			name:    "defaults",
+			key:     SourceKey,
+			wantKey: []byte("eu.created"),
+			want: []kafka.Header{
+				{Key: headerContentType, Value: []byte("application/json")},
+				{Key: headerMessageID, Value: []byte("m-1")},
+				{Key: kafka.HeaderCorrelationID, Value: []byte("c-1")},
+				{Key: kafka.HeaderEventType, Value: []byte("OrderCreated")},
+				{Key: "at", Value: []byte("2024-05-01T12:00:00Z")},
+				{Key: "attempt", Value: []byte("2")},
+				{Key: "raw", Value: []byte{0xff}},
+				{Key: "tenant", Value: []byte("acme")},
+				{Key: HeaderBridgedFrom, Value: []byte("rabbitmq:orders")},
+			},
+		},
+		{
+			name:    "header key, include and rename",
+			key:     KeySource{kind: keyHeader, value: "tenant"},
+			headers: HeaderMapping{Include: []string{"tenant", headerMessageID}, Rename: map[string]string{"tenant": "x-tenant"}},
+			wantKey: []byte("acme"),
+			want: []kafka.Header{
+				{Key: headerMessageID, Value: []byte("m-1")},
+				{Key: "x-tenant", Value: []byte("acme")},
+				{Key: HeaderBridgedFrom, Value: []byte("rabbitmq:orders")},
+			},
+		},
+		{
+			name:    "static key and exclude",
+			key:     KeySource{kind: keyStatic, value: "all"},
+			headers: HeaderMapping{Exclude: []string{headerContentType, "raw", "at", "attempt", "tenant"}},
+			wantKey: []byte("all"),
+			want: []kafka.Header{
+				{Key: headerMessageID, Value: []byte("m-1")},
+				{Key: kafka.HeaderCorrelationID, Value: []byte("c-1")},
+				{Key: kafka.HeaderEventType, Value: []byte("OrderCreated")},
+				{Key: HeaderBridgedFrom, Value: []byte("rabbitmq:orders")},
+			},
+		},
+		{
+			name:    "no key, missing header key",
+			key:     KeySource{kind: keyHeader, value: "region"},
+			headers: HeaderMapping{Include: []string{"none"}},
+			want: []kafka.Header{
+				{Key: HeaderBridgedFrom, Value: []byte("rabbitmq:orders")},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m := toKafka(d, tt.key, tt.headers)
+			if !reflect.DeepEqual(m.Key, tt.wantKey) {
+				t.Errorf("key = %q, want %q", m.Key, tt.wantKey)
+			}
+			if string(m.Value) != `{"id":1}` || !m.Time.Equal(ts) {
+				t.Errorf("value %q at %v, want the body at %v", m.Value, m.Time, ts)
+			}
+			if !reflect.DeepEqual(m.Headers, tt.want) {
+				t.Errorf("headers\n got %q\nwant %q", m.Headers, tt.want)
+			}
+		})
+	}
+}
+
+func TestToAMQP(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := kafka.Message{
+		Topic: "payments",
+		Key:   []byte("p-7"),
+		Value: []byte(`{"id":7}`),
+		Time:  ts,
+		Headers: []kafka.Header{
+			{Key: headerContentType, Value: []byte("application/json")},
+			{Key: headerMessageID, Value: []byte("m-7")},
+			{Key: kafka.HeaderCorrelationID, Value: []byte("c-7")},
+			{Key: kafka.HeaderEventType, Value: []byte("PaymentTaken")},
+			{Key: "tenant", Value: []byte("acme")},
+			{Key: "raw", Value: []byte{0xff, 0xfe}},
+			{Key: HeaderBridgedFrom, Value: []byte("rabbitmq:orders")},
+		},
+	}
+	for _, tt := range []struct {
+		name        string
+		key         KeySource
+		headers     HeaderMapping
+		wantKey     string
+		wantProps   amqp.Publishing
+		wantHeaders amqp.Table
+	}{
+		{
+			name:    "defaults",
+			key:     SourceKey,
+			wantKey: "p-7",
+			wantProps: amqp.Publishing{
+				ContentType:   "application/json",
+				MessageId:     "m-7",
+				CorrelationId: "c-7",
+				Type:          "PaymentTaken",
+			},
+			wantHeaders: amqp.Table{
+				"tenant":          "acme",
+				"raw":             []byte{0xff, 0xfe},
+				HeaderBridgedFrom: "kafka:payments",
+			},
+		},
+		{
+			name:    "header key, rename and exclude",
+			key:     KeySource{kind: keyHeader, value: "tenant"},
+			headers: HeaderMapping{Exclude: []string{"raw", kafka.HeaderEventType}, Rename: map[string]string{"tenant": "x-tenant"}},
+			wantKey: "acme",
+			wantProps: amqp.Publishing{
+				ContentType:   "application/json",
+				MessageId:     "m-7",
+				CorrelationId: "c-7",
+			},
+			wantHeaders: amqp.Table{
+				"x-tenant":        "acme",
+				HeaderBridgedFrom: "kafka:payments",
+			},
+		},
+		{
+			name:        "no key, nothing included",
+			key:         KeySource{kind: keyNone},
+			headers:     HeaderMapping{Include: []string{"none"}},
+			wantHeaders: amqp.Table{HeaderBridgedFrom: "kafka:payments"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p, key := toAMQP(m, tt.key, tt.headers)
+			if key != tt.wantKey {
+				t.Errorf("routing key = %q, want %q", key, tt.wantKey)
+			}
+			if p.ContentType != tt.wantProps.ContentType || p.MessageId != tt.wantProps.MessageId ||
+				p.CorrelationId != tt.wantProps.CorrelationId || p.Type != tt.wantProps.Type {
+				t.Errorf("properties = %q %q %q %q, want %q %q %q %q",
+					p.ContentType, p.MessageId, p.CorrelationId, p.Type,
+					tt.wantProps.ContentType, tt.wantProps.MessageId, tt.wantProps.CorrelationId, tt.wantProps.Type)
+			}
+			if string(p.Body) != `{"id":7}` || !p.Timestamp.Equal(ts) || p.DeliveryMode != amqp.Persistent {
+				t.Errorf("body %q at %v mode %v, want the value at %v, persistent", p.Body, p.Timestamp, p.DeliveryMode, ts)
+			}
+			if !reflect.DeepEqual(p.Headers, tt.wantHeaders) {
+				t.Errorf("headers = %v, want %v", p.Headers, tt.wantHeaders)
+			}
+		})
+	}
+}
+
+func TestLoopSuppression(t *testing.T) {
+	delivery := func(from any) amqp.Delivery {
+		d := amqp.Delivery{Exchange: "orders", Headers: amqp.Table{}}
+		if from != nil {
+			d.Headers[HeaderBridgedFrom] = from
+		}
+		return d
+	}
+	record := func(from string) kafka.Message {
+		m := kafka.Message{Topic: "orders"}
+		if from != "" {
+			m.Headers = []kafka.Header{{Key: HeaderBridgedFrom, Value: []byte(from)}}
+		}
+		return m
+	}
+
+	for _, tt := range []struct {
+		name  string
+		d     amqp.Delivery
+		topic string
+		loops bool
+	}{
+		{"not bridged", delivery(nil), "payments", false},
+		{"bridged from the same topic", delivery("kafka:payments"), "payments", true},
+		{"bridged from another topic", delivery("kafka:refunds"), "payments", false},
+		{"bridged from an exchange of that name", delivery("rabbitmq:payments"), "payments", false},
+		{"header of another type", delivery([]byte("kafka:payments")), "payments", false},
+	} {
+		if got := cameFromTopic(tt.d, tt.topic); got != tt.loops {
+			t.Errorf("to kafka, %v: loops = %v, want %v", tt.name, got, tt.loops)
+		}
+	}
+
+	for _, tt := range []struct {
+		name     string
+		m        kafka.Message
+		exchange string
+		loops    bool
+	}{
+		{"not bridged", record(""), "orders", false},
+		{"bridged from the same exchange", record("rabbitmq:orders"), "orders", true},
+		{"bridged from another exchange", record("rabbitmq:refunds"), "orders", false},
+		{"bridged from a topic of that name", record("kafka:orders"), "orders", false},
+		{"bridged from the default exchange", record("rabbitmq:"), "", true},
+		{"not bridged, default exchange", record(""), "", false},
+	} {
+		if got := cameFromExchange(tt.m, tt.exchange); got != tt.loops {
+			t.Errorf("to rabbitmq, %v: loops = %v, want %v", tt.name, got, tt.loops)
+		}
+	}
+
+	// what one direction writes is what the other one suppresses
+	if m := toKafka(delivery(nil), SourceKey, HeaderMapping{}); !cameFromExchange(m, "orders") {
+		t.Errorf("record bridged from exchange orders is bridged back to it")
+	}
+	p, _ := toAMQP(record(""), SourceKey, HeaderMapping{})
+	if !cameFromTopic(amqp.Delivery{Headers: p.Headers}, "orders") {
+		t.Errorf("publishing bridged from topic orders is bridged back to it")
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bridge/bridge"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"os/signal"
+	"producer/kafka"
+	"publisher/logger"
+	"publisher/rabbitmq"
+	"publisher/utils"
+	"strings"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+// A config file lists the routes, for example:
+//
+//	{
+//	  "to_kafka": [
+//	    {"queue": "orders", "topic": "orders", "key": "key",
+//	     "headers": {"exclude": ["x-internal"]}}
+//	  ],
+//	  "to_rabbitmq": [
+//	    {"topic": "usernames", "group": "bridge", "exchange": "events",
+//	     "routing_key": "header:event-type", "headers": {"rename": {"traceparent": "x-traceparent"}}}
+//	  ]
+//	}
+func main() {
+	log := logger.Get()
+
+	if err := godotenv.Load(); err != nil {
+		log.Warn("no .env file loaded", "error", err)
+	}
+
+	var (
+		url     string
+		brokers string
+		config  string
+	)
+	flag.StringVar(&url, "url", os.Getenv(utils.URL), "amqp url, defaults to the url env variable")
+	flag.StringVar(&brokers, "brokers", "localhost:19092", "comma separated kafka broker addresses")
+	flag.StringVar(&config, "config", "bridge.json", "JSON file with the routes to bridge")
+	flag.Parse()
+
+	data, err := os.ReadFile(config)
+	if err != nil {
+		log.Fatal("error reading config", "error", err)
+	}
+	var cfg bridge.Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Fatal("invalid config", "file", config, "error", err)
+	}
+	cfg.Rabbitmq = rabbitmq.Rabbitmq{
+		Url:         url,
+		Credentials: rabbitmq.CredentialsFromEnv(),
+	}
+	cfg.Kafka = []kafka.Option{
+		kafka.WithBrokers(strings.Split(brokers, ",")...),
+		kafka.WithClientID("bridge"),
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := bridge.Run(ctx, cfg, log); err != nil {
+		log.Fatal("bridge stopped", "error", err)
+	}
+	log.Info("bridge stopped")
+}
@@ -49,9 +49,10 @@ func DirectExchangeNormalQueue(ctx context.Context, log *logger.Logger) error {
 		}
 
 		rbmq := rabbitmq.Rabbitmq{
-			Exchange: directEx,
-			Q:        []rabbitmq.Queue{q1, q2},
-			Url:      url,
+			Exchange:    directEx,
+			Q:           []rabbitmq.Queue{q1, q2},
+			Url:         url,
+			Credentials: rabbitmq.CredentialsFromEnv(),
 		}
 
 		if err := rbmq.Init(); err != nil {
@@ -166,9 +167,10 @@ func TopicExchangeQuorumQueue(ctx context.Context, log *logger.Logger) error {
 		}
 
 		rbmq := rabbitmq.Rabbitmq{
-			Exchange: directEx,
-			Q:        []rabbitmq.Queue{q1, q2},
-			Url:      url,
+			Exchange:    directEx,
+			Q:           []rabbitmq.Queue{q1, q2},
+			Url:         url,
+			Credentials: rabbitmq.CredentialsFromEnv(),
 		}
 
 		if err := rbmq.Init(); err != nil {
@@ -269,9 +271,10 @@ func FanoutExchangeNormalQueue(ctx context.Context, log *logger.Logger) error {
 		}
 
 		rbmq := rabbitmq.Rabbitmq{
-			Exchange: ex,
-			Q:        []rabbitmq.Queue{q1, q2},
-			Url:      url,
+			Exchange:    ex,
+			Q:           []rabbitmq.Queue{q1, q2},
+			Url:         url,
+			Credentials: rabbitmq.CredentialsFromEnv(),
 		}
 
 		if err := rbmq.Init(); err != nil {
@@ -347,7 +350,6 @@ func FanoutExchangeNormalQueue(ctx context.Context, log *logger.Logger) error {
 		}
 
 	}
-	return nil
 }
 
 func HeaderExchangeNormalQueue(ctx context.Context, log *logger.Logger) error {
@@ -380,9 +382,10 @@ func HeaderExchangeNormalQueue(ctx context.Context, log *logger.Logger) error {
 		}
 
 		rbmq := rabbitmq.Rabbitmq{
-			Exchange: ex,
-			Q:        []rabbitmq.Queue{q1, q2},
-			Url:      url,
+			Exchange:    ex,
+			Q:           []rabbitmq.Queue{q1, q2},
+			Url:         url,
+			Credentials: rabbitmq.CredentialsFromEnv(),
 		}
 
 		if err := rbmq.Init(); err != nil {
@@ -458,5 +461,4 @@ func HeaderExchangeNormalQueue(ctx context.Context, log *logger.Logger) error {
 		}
 
 	}
-	return nil
 }
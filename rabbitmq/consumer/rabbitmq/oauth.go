@@ -0,0 +1,245 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"consumer/utils"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Token is an access token handed to the broker as the AMQP password.
+type Token struct {
+	AccessToken string
+	Expiry      time.Time
+}
+
+// CredentialProvider supplies the secret used to authenticate a connection.
+// Implementations are called once on Init and again before each refresh.
+type CredentialProvider interface {
+	Token(ctx context.Context) (Token, error)
+}
+
+// ClientCredentials fetches JWTs from an OAuth2 token endpoint using the
+// client-credentials grant, as expected by RabbitMQ's OAuth2 auth backend.
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	HTTPClient   *http.Client
+}
+
+// CredentialsFromEnv returns a ClientCredentials provider when a token url is
+// configured, or nil so that the static url is used as-is.
+func CredentialsFromEnv() CredentialProvider {
+	tokenURL := os.Getenv(utils.TOKEN_URL)
+	if tokenURL == "" {
+		return nil
+	}
+	var scopes []string
+	if s := os.Getenv(utils.SCOPES); s != "" {
+		scopes = strings.Fields(s)
+	}
+	return &ClientCredentials{
+		TokenURL:     tokenURL,
+		ClientID:     os.Getenv(utils.CLIENT_ID),
+		ClientSecret: os.Getenv(utils.CLIENT_SECRET),
+		Scopes:       scopes,
+	}
+}
+
+// tokenTimeout bounds every token request, so a hung endpoint can't stall
+// Init or the refresh loop.
+const tokenTimeout = 30 * time.Second
+
+// maxErrorBody caps how much of an error response is read.
+const maxErrorBody = 64 << 10
+
+// defaultHTTPClient is used when ClientCredentials has no HTTPClient.
+var defaultHTTPClient = &http.Client{Timeout: tokenTimeout}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (c *ClientCredentials) Token(ctx context.Context) (Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	client := c.HTTPClient
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Token{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// error bodies are often HTML or plain text from a proxy, so only
+		// use them when they are an OAuth2 error response
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		var tr tokenResponse
+		if json.Unmarshal(body, &tr) != nil {
+			if text := strings.TrimSpace(string(body)); text != "" {
+				return Token{}, fmt.Errorf("token endpoint returned %v: %v", resp.StatusCode, text)
+			}
+			return Token{}, fmt.Errorf("token endpoint returned %v", resp.StatusCode)
+		}
+		if tr.Description != "" {
+			return Token{}, fmt.Errorf("token endpoint returned %v: %v: %v", resp.StatusCode, tr.Error, tr.Description)
+		}
+		if tr.Error != "" {
+			return Token{}, fmt.Errorf("token endpoint returned %v: %v", resp.StatusCode, tr.Error)
+		}
+		return Token{}, fmt.Errorf("token endpoint returned %v", resp.StatusCode)
+	}
+
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return Token{}, fmt.Errorf("decoding token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return Token{}, errors.New("token endpoint returned an empty access token")
+	}
+
+	tok := Token{AccessToken: tr.AccessToken}
+	if tr.ExpiresIn > 0 {
+		tok.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+// refreshSkew is how long before expiry a token gets replaced.
+const refreshSkew = 30 * time.Second
+
+// refreshRetry is the delay between failed refresh attempts.
+const refreshRetry = 5 * time.Second
+
+// minRefreshWait keeps a provider issuing very short-lived tokens from
+// being asked for new ones in a tight loop.
+const minRefreshWait = time.Second
+
+// secretUpdater is the part of *amqp.Connection a tokenRefresher uses.
+type secretUpdater interface {
+	NotifyClose(chan *amqp.Error) chan *amqp.Error
+	UpdateSecret(newSecret, reason string) error
+}
+
+type tokenRefresher struct {
+	provider CredentialProvider
+	conn     secretUpdater
+	onError  func(error)
+}
+
+func (t *tokenRefresher) run(expiry time.Time) {
+	closed := t.conn.NotifyClose(make(chan *amqp.Error, 1))
+	if expiry.IsZero() {
+		// tokens without an expiry never need to be refreshed
+		return
+	}
+	wait := refreshWait(expiry)
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-closed:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		tok, err := fetchToken(t.provider)
+		if err == nil {
+			err = t.conn.UpdateSecret(tok.AccessToken, "token refresh")
+		}
+		if err != nil {
+			if t.onError != nil {
+				t.onError(err)
+			}
+			// try again shortly; once the old token lapses the broker closes
+			// the connection and the loop exits
+			wait = refreshRetry
+			continue
+		}
+		if tok.Expiry.IsZero() {
+			return
+		}
+		wait = refreshWait(tok.Expiry)
+	}
+}
+
+// refreshWait is how long to wait before replacing a token that expires at
+// expiry: until refreshSkew before then, or halfway for tokens that don't
+// live twice as long, but never less than minRefreshWait.
+func refreshWait(expiry time.Time) time.Duration {
+	left := time.Until(expiry)
+	if left > 2*refreshSkew {
+		return left - refreshSkew
+	}
+	return max(left/2, minRefreshWait)
+}
+
+func fetchToken(p CredentialProvider) (Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenTimeout)
+	defer cancel()
+	return p.Token(ctx)
+}
+
+// dialWithCredentials dials r.Url, replacing its password with a token from
+// r.Credentials, and keeps the token fresh for the life of the connection.
+func (r *Rabbitmq) dialWithCredentials() (*amqp.Connection, error) {
+	u, err := amqp.ParseURI(r.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := fetchToken(r.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("fetching token: %w", err)
+	}
+
+	conn, err := amqp.DialConfig(r.Url, amqp.Config{
+		SASL: []amqp.Authentication{&amqp.PlainAuth{
+			Username: u.Username,
+			Password: tok.AccessToken,
+		}},
+		Vhost: u.Vhost,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refresher := &tokenRefresher{
+		provider: r.Credentials,
+		conn:     conn,
+		onError:  r.OnRefreshError,
+	}
+	go refresher.run(tok.Expiry)
+	return conn, nil
+}
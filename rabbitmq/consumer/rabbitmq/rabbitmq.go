@@ -23,6 +23,12 @@ type Rabbitmq struct {
 	Q          []Queue
 	Connection *amqp.Connection
 	Url        string
+	// Credentials, when set, replaces the password in Url with a token that
+	// is refreshed on the open connection before it expires.
+	Credentials CredentialProvider
+	// OnRefreshError is called when a token refresh fails; refreshing is
+	// retried until the token expires and the broker closes the connection.
+	OnRefreshError func(error)
 }
 
 type Message struct {
@@ -30,6 +36,15 @@ type Message struct {
 }
 
 func (r *Rabbitmq) Init() error {
+	if r.Credentials != nil {
+		conn, err := r.dialWithCredentials()
+		if err != nil {
+			return err
+		}
+		r.Connection = conn
+		return nil
+	}
+
 	conn, err := amqp.Dial(r.Url)
 	if err != nil {
 		return err
@@ -0,0 +1,321 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	crabbitmq "consumer/rabbitmq"
+	prabbitmq "publisher/rabbitmq"
+	putils "publisher/utils"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// drainTimeout bounds how long producers wait for outstanding confirms once
+// the run is over.
+const drainTimeout = 5 * time.Second
+
+type counters struct {
+	published atomic.Int64
+	confirmed atomic.Int64
+	nacked    atomic.Int64
+	consumed  atomic.Int64
+}
+
+// Run declares the benchmark topology, runs the configured producers and
+// consumers for cfg.Duration and reports what they observed.
+func Run(ctx context.Context, cfg Config) (Report, error) {
+	if err := cfg.Validate(); err != nil {
+		return Report{}, err
+	}
+	if err := setup(cfg); err != nil {
+		return Report{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		c    counters
+		rec  Recorder
+		once sync.Once
+		rerr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			rerr = err
+			cancel()
+		})
+	}
+
+	ready := make(chan struct{}, cfg.Consumers)
+	for i := 0; i < cfg.Consumers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := consume(ctx, cfg, &c, &rec, ready); err != nil {
+				fail(err)
+			}
+		}()
+	}
+	// producers only start once every consumer is subscribed so that the
+	// first messages are not counted as queueing latency
+	for i := 0; i < cfg.Consumers; i++ {
+		select {
+		case <-ready:
+		case <-ctx.Done():
+		}
+	}
+
+	start := time.Now()
+	for i := 0; i < cfg.Producers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := produce(ctx, cfg, &c); err != nil {
+				fail(err)
+			}
+		}()
+	}
+	<-ctx.Done()
+	elapsed := time.Since(start)
+	wg.Wait()
+
+	if rerr != nil {
+		return Report{}, rerr
+	}
+	return report(cfg, &c, &rec, elapsed), nil
+}
+
+func setup(cfg Config) error {
+	q := prabbitmq.Queue{
+		Name:       cfg.Queue,
+		RoutingKey: cfg.Queue,
+		Args: amqp.Table{
+			"x-queue-type": string(cfg.QueueType),
+		},
+	}
+	rbmq := prabbitmq.Rabbitmq{
+		Exchange: prabbitmq.Exchange{
+			Name: cfg.Exchange,
+			Type: putils.DIRECT,
+		},
+		Q:           []prabbitmq.Queue{q},
+		Url:         cfg.Url,
+		Credentials: prabbitmq.CredentialsFromEnv(),
+	}
+	if err := rbmq.Init(); err != nil {
+		return err
+	}
+	defer rbmq.Connection.Close()
+
+	ch, err := rbmq.Connection.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if err := rbmq.Exchange.CreateExchange(ch); err != nil {
+		return err
+	}
+	if _, err := q.CreateQueue(ch); err != nil {
+		return err
+	}
+	return rbmq.Bind(ch)
+}
+
+func produce(ctx context.Context, cfg Config, c *counters) error {
+	rbmq := prabbitmq.Rabbitmq{
+		Url:         cfg.Url,
+		Credentials: prabbitmq.CredentialsFromEnv(),
+	}
+	if err := rbmq.Init(); err != nil {
+		return err
+	}
+	defer rbmq.Connection.Close()
+
+	ch, err := rbmq.Connection.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if cfg.Confirm {
+		if err := ch.Confirm(false); err != nil {
+			return err
+		}
+	}
+
+	var tick <-chan time.Time
+	if cfg.Rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(cfg.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	settle := func(ctx context.Context, dc *amqp.DeferredConfirmation) error {
+		ok, err := dc.WaitContext(ctx)
+		if err != nil {
+			return err
+		}
+		if ok {
+			c.confirmed.Add(1)
+		} else {
+			c.nacked.Add(1)
+		}
+		return nil
+	}
+
+	body := make([]byte, cfg.Size)
+	var pending []*amqp.DeferredConfirmation
+	for ctx.Err() == nil {
+		if tick != nil {
+			select {
+			case <-ctx.Done():
+				continue
+			case <-tick:
+			}
+		}
+
+		stamp(body)
+		msg := amqp.Publishing{
+			ContentType: "application/octet-stream",
+			Body:        body,
+		}
+		if !cfg.Confirm {
+			if err := ch.PublishWithContext(ctx, cfg.Exchange, cfg.Queue, false, false, msg); err != nil {
+				return ignoreDone(ctx, err)
+			}
+			c.published.Add(1)
+			continue
+		}
+
+		dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, cfg.Exchange, cfg.Queue, false, false, msg)
+		if err != nil {
+			return ignoreDone(ctx, err)
+		}
+		c.published.Add(1)
+		pending = append(pending, dc)
+		if len(pending) >= cfg.ConfirmWindow {
+			if err := settle(ctx, pending[0]); err != nil {
+				return ignoreDone(ctx, err)
+			}
+			pending = pending[1:]
+		}
+	}
+
+	drain, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	for _, dc := range pending {
+		if err := settle(drain, dc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func consume(ctx context.Context, cfg Config, c *counters, rec *Recorder, ready chan<- struct{}) error {
+	rbmq := crabbitmq.Rabbitmq{
+		Url:         cfg.Url,
+		Credentials: crabbitmq.CredentialsFromEnv(),
+	}
+	if err := rbmq.Init(); err != nil {
+		return err
+	}
+	defer rbmq.Connection.Close()
+
+	ch, err := rbmq.Connection.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if cfg.Prefetch > 0 {
+		if err := ch.Qos(cfg.Prefetch, 0, false); err != nil {
+			return err
+		}
+	}
+
+	var args amqp.Table
+	if cfg.QueueType == STREAM {
+		args = amqp.Table{"x-stream-offset": "next"}
+	}
+	msgs, err := ch.Consume(
+		cfg.Queue,
+		"",
+		false, // manual ACK
+		false,
+		false,
+		false,
+		args,
+	)
+	if err != nil {
+		return err
+	}
+	ready <- struct{}{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m, ok := <-msgs:
+			if !ok {
+				return ignoreDone(ctx, errors.New("delivery channel closed"))
+			}
+			if d, ok := latency(m.Body, time.Now()); ok {
+				rec.Add(d)
+			}
+			c.consumed.Add(1)
+			if err := m.Ack(false); err != nil {
+				return ignoreDone(ctx, err)
+			}
+		}
+	}
+}
+
+// ignoreDone swallows errors caused by the run ending.
+func ignoreDone(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func report(cfg Config, c *counters, rec *Recorder, elapsed time.Duration) Report {
+	ps, max := rec.Percentiles(percentiles...)
+	secs := elapsed.Seconds()
+	mib := float64(cfg.Size) / (1 << 20)
+
+	r := Report{
+		QueueType: cfg.QueueType,
+		Producers: cfg.Producers,
+		Consumers: cfg.Consumers,
+		Size:      cfg.Size,
+		Confirm:   cfg.Confirm,
+		Prefetch:  cfg.Prefetch,
+		Elapsed:   elapsed,
+		Published: c.published.Load(),
+		Confirmed: c.confirmed.Load(),
+		Nacked:    c.nacked.Load(),
+		Consumed:  c.consumed.Load(),
+		Latency: Latency{
+			P50:  ps[0],
+			P75:  ps[1],
+			P95:  ps[2],
+			P99:  ps[3],
+			P999: ps[4],
+			Max:  max,
+		},
+	}
+	if secs > 0 {
+		r.PublishRate = float64(r.Published) / secs
+		r.ConsumeRate = float64(r.Consumed) / secs
+		r.PublishMBps = r.PublishRate * mib
+		r.ConsumeMBps = r.ConsumeRate * mib
+	}
+	return r
+}
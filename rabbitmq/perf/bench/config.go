@@ -0,0 +1,65 @@
+package bench
+
+import (
+	"fmt"
+	"time"
+)
+
+type QueueType string
+
+const (
+	CLASSIC QueueType = "classic"
+	QUORUM  QueueType = "quorum"
+	STREAM  QueueType = "stream"
+)
+
+// Config describes a single benchmark run.
+type Config struct {
+	Url       string
+	Exchange  string
+	Queue     string
+	QueueType QueueType
+
+	Producers int
+	Consumers int
+	// Size is the message body size in bytes, including the 8 byte
+	// timestamp used to measure latency.
+	Size int
+	// Rate limits each producer to this many messages per second; 0 means
+	// publish as fast as possible.
+	Rate int
+	// Confirm enables publisher confirms, keeping at most ConfirmWindow
+	// messages unconfirmed per producer.
+	Confirm       bool
+	ConfirmWindow int
+	Prefetch      int
+	Duration      time.Duration
+}
+
+func (c Config) Validate() error {
+	switch c.QueueType {
+	case CLASSIC, QUORUM, STREAM:
+	default:
+		return fmt.Errorf("unknown queue type %q", c.QueueType)
+	}
+	if c.Producers < 0 || c.Consumers < 0 || c.Producers+c.Consumers == 0 {
+		return fmt.Errorf("need at least one producer or consumer")
+	}
+	// the publish interval is a whole number of nanoseconds
+	if c.Rate < 0 || c.Rate > int(time.Second) {
+		return fmt.Errorf("rate must be between 0 and %v messages per second", int(time.Second))
+	}
+	if c.Size < timestampSize {
+		return fmt.Errorf("message size must be at least %v bytes", timestampSize)
+	}
+	if c.Confirm && c.ConfirmWindow < 1 {
+		return fmt.Errorf("confirm window must be at least 1")
+	}
+	if c.QueueType == STREAM && c.Prefetch < 1 {
+		return fmt.Errorf("stream queues require a prefetch")
+	}
+	if c.Duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	return nil
+}
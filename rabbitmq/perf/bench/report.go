@@ -0,0 +1,77 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+var percentiles = []float64{50, 75, 95, 99, 99.9}
+
+type Latency struct {
+	P50  time.Duration `json:"p50"`
+	P75  time.Duration `json:"p75"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	P999 time.Duration `json:"p999"`
+	Max  time.Duration `json:"max"`
+}
+
+// Report is the outcome of a run. Durations are encoded in nanoseconds.
+type Report struct {
+	QueueType QueueType     `json:"queue_type"`
+	Producers int           `json:"producers"`
+	Consumers int           `json:"consumers"`
+	Size      int           `json:"size"`
+	Confirm   bool          `json:"confirm"`
+	Prefetch  int           `json:"prefetch"`
+	Elapsed   time.Duration `json:"elapsed"`
+
+	Published int64 `json:"published"`
+	Confirmed int64 `json:"confirmed"`
+	Nacked    int64 `json:"nacked"`
+	Consumed  int64 `json:"consumed"`
+
+	PublishRate float64 `json:"publish_rate"`
+	ConsumeRate float64 `json:"consume_rate"`
+	// PublishMBps and ConsumeMBps are throughput in MiB per second.
+	PublishMBps float64 `json:"publish_mbps"`
+	ConsumeMBps float64 `json:"consume_mbps"`
+
+	Latency Latency `json:"latency"`
+}
+
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "queue type\t%v\n", r.QueueType)
+	fmt.Fprintf(tw, "producers/consumers\t%v/%v\n", r.Producers, r.Consumers)
+	fmt.Fprintf(tw, "message size\t%v B\n", r.Size)
+	fmt.Fprintf(tw, "confirm\t%v\n", r.Confirm)
+	fmt.Fprintf(tw, "prefetch\t%v\n", r.Prefetch)
+	fmt.Fprintf(tw, "elapsed\t%v\n", r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintln(tw, "\t")
+	fmt.Fprintln(tw, "\tmessages\tmsg/s\tMiB/s")
+	fmt.Fprintf(tw, "published\t%v\t%.0f\t%.2f\n", r.Published, r.PublishRate, r.PublishMBps)
+	if r.Confirm {
+		fmt.Fprintf(tw, "confirmed\t%v\t\t\n", r.Confirmed)
+		fmt.Fprintf(tw, "nacked\t%v\t\t\n", r.Nacked)
+	}
+	fmt.Fprintf(tw, "consumed\t%v\t%.0f\t%.2f\n", r.Consumed, r.ConsumeRate, r.ConsumeMBps)
+	fmt.Fprintln(tw, "\t")
+	fmt.Fprintln(tw, "latency\tp50\tp75\tp95\tp99\tp99.9\tmax")
+	l := r.Latency
+	fmt.Fprintf(tw, "\t%v\t%v\t%v\t%v\t%v\t%v\n", us(l.P50), us(l.P75), us(l.P95), us(l.P99), us(l.P999), us(l.Max))
+	return tw.Flush()
+}
+
+func us(d time.Duration) time.Duration {
+	return d.Round(time.Microsecond)
+}
@@ -0,0 +1,57 @@
+package bench
+
+import (
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+)
+
+const timestampSize = 8
+
+// stamp writes the current time into the head of body.
+func stamp(body []byte) {
+	binary.BigEndian.PutUint64(body, uint64(time.Now().UnixNano()))
+}
+
+// latency reads the publish time back out of body.
+func latency(body []byte, now time.Time) (time.Duration, bool) {
+	if len(body) < timestampSize {
+		return 0, false
+	}
+	sent := int64(binary.BigEndian.Uint64(body))
+	return now.Sub(time.Unix(0, sent)), true
+}
+
+// Recorder collects latency samples from concurrent consumers.
+type Recorder struct {
+	mu      sync.Mutex
+	samples []time.Duration
+}
+
+func (r *Recorder) Add(d time.Duration) {
+	r.mu.Lock()
+	r.samples = append(r.samples, d)
+	r.mu.Unlock()
+}
+
+// Percentiles sorts the recorded samples and returns the value at each of
+// the requested percentiles (0-100), followed by the maximum.
+func (r *Recorder) Percentiles(ps ...float64) ([]time.Duration, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]time.Duration, len(ps))
+	if len(r.samples) == 0 {
+		return out, 0
+	}
+	sort.Slice(r.samples, func(i, j int) bool { return r.samples[i] < r.samples[j] })
+	for i, p := range ps {
+		idx := int(p / 100 * float64(len(r.samples)))
+		if idx >= len(r.samples) {
+			idx = len(r.samples) - 1
+		}
+		out[i] = r.samples[idx]
+	}
+	return out, r.samples[len(r.samples)-1]
+}
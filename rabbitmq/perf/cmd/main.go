@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"perf/bench"
+	"publisher/logger"
+	"publisher/utils"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	log := logger.Get()
+
+	if err := godotenv.Load(); err != nil {
+		log.Warn("no .env file loaded", "error", err)
+	}
+
+	var (
+		cfg       bench.Config
+		queueType string
+		jsonOut   string
+	)
+	flag.StringVar(&cfg.Url, "url", os.Getenv(utils.URL), "amqp url, defaults to the url env variable")
+	flag.StringVar(&cfg.Exchange, "exchange", "perf-test", "exchange to publish to")
+	flag.StringVar(&cfg.Queue, "queue", "", "queue name, defaults to perf-test-<queue-type>")
+	flag.StringVar(&queueType, "queue-type", string(bench.CLASSIC), "classic, quorum or stream")
+	flag.IntVar(&cfg.Producers, "producers", 1, "number of producers")
+	flag.IntVar(&cfg.Consumers, "consumers", 1, "number of consumers")
+	flag.IntVar(&cfg.Size, "size", 1024, "message size in bytes")
+	flag.IntVar(&cfg.Rate, "rate", 0, "messages per second per producer, 0 for unlimited")
+	flag.BoolVar(&cfg.Confirm, "confirm", false, "use publisher confirms")
+	flag.IntVar(&cfg.ConfirmWindow, "confirm-window", 100, "max unconfirmed messages per producer")
+	flag.IntVar(&cfg.Prefetch, "prefetch", 100, "consumer prefetch, 0 for unlimited")
+	flag.DurationVar(&cfg.Duration, "duration", 30*time.Second, "how long to run")
+	flag.StringVar(&jsonOut, "json", "", "also write the report as JSON to this file, - for stdout")
+	flag.Parse()
+
+	cfg.QueueType = bench.QueueType(queueType)
+	if cfg.Queue == "" {
+		cfg.Queue = "perf-test-" + queueType
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	log.Info("Starting rabbitmq benchmark", "queue", cfg.Queue, "type", cfg.QueueType, "duration", cfg.Duration)
+	report, err := bench.Run(ctx, cfg)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if jsonOut == "-" {
+		if err := report.WriteJSON(os.Stdout); err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
+	if err := report.WriteTable(os.Stdout); err != nil {
+		log.Fatal(err.Error())
+	}
+	if jsonOut != "" {
+		f, err := os.Create(jsonOut)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		defer f.Close()
+		if err := report.WriteJSON(f); err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+}
@@ -3,11 +3,9 @@ module publisher
 go 1.24.7
 
 require (
+	github.com/joho/godotenv v1.5.1
 	github.com/rabbitmq/amqp091-go v1.10.0
 	go.uber.org/zap v1.27.1
 )
 
-require (
-	github.com/joho/godotenv v1.5.1 // indirect
-	go.uber.org/multierr v1.11.0 // indirect
-)
+require go.uber.org/multierr v1.11.0 // indirect
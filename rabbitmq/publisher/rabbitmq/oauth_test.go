@@ -0,0 +1,180 @@
+package rabbitmq
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"publisher/rabbitmq/oauthtest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestClientCredentialsToken(t *testing.T) {
+	srv := oauthtest.NewServer("bridge", "s3cret")
+	defer srv.Close()
+
+	c := &ClientCredentials{TokenURL: srv.TokenURL(), ClientID: "bridge", ClientSecret: "s3cret", Scopes: []string{"rabbitmq.read:*/*"}}
+	tok, err := c.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued := srv.Issued()
+	if len(issued) != 1 || tok.AccessToken != issued[0] {
+		t.Fatalf("token %q, issued %q", tok.AccessToken, issued)
+	}
+	if until := time.Until(tok.Expiry); until < 59*time.Minute || until > time.Hour {
+		t.Fatalf("token expires in %v, want an hour", until)
+	}
+
+	c.ClientSecret = "wrong"
+	if _, err := c.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Fatalf("wrong secret: err = %v, want invalid_client", err)
+	}
+}
+
+func TestClientCredentialsErrorStatus(t *testing.T) {
+	srv := oauthtest.NewServer("bridge", "s3cret")
+	defer srv.Close()
+	srv.SetFailing(true)
+
+	c := &ClientCredentials{TokenURL: srv.TokenURL(), ClientID: "bridge", ClientSecret: "s3cret"}
+	_, err := c.Token(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "500") || !strings.Contains(err.Error(), "server_error") {
+		t.Fatalf("err = %v, want the status and the OAuth2 error", err)
+	}
+
+	// a proxy answering with plain text keeps its status in the error
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer plain.Close()
+	c.TokenURL = plain.URL
+	_, err = c.Token(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "502") || !strings.Contains(err.Error(), "bad gateway") {
+		t.Fatalf("err = %v, want the status and the body", err)
+	}
+}
+
+// fakeConn records the secrets a refresher hands to the connection.
+type fakeConn struct {
+	mu      sync.Mutex
+	secrets []string
+	updated chan struct{}
+	closed  chan *amqp.Error
+}
+
+func (f *fakeConn) NotifyClose(c chan *amqp.Error) chan *amqp.Error {
+	f.closed = c
+	return c
+}
+
+func (f *fakeConn) UpdateSecret(secret, reason string) error {
+	f.mu.Lock()
+	f.secrets = append(f.secrets, secret)
+	f.mu.Unlock()
+	f.updated <- struct{}{}
+	return nil
+}
+
+func TestRefreshBeforeExpiry(t *testing.T) {
+	srv := oauthtest.NewServer("bridge", "s3cret")
+	defer srv.Close()
+	// tokens this short-lived are replaced halfway, one second from now
+	srv.ExpiresIn = 2 * time.Second
+
+	c := &ClientCredentials{TokenURL: srv.TokenURL(), ClientID: "bridge", ClientSecret: "s3cret"}
+	first, err := c.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := &fakeConn{updated: make(chan struct{}, 1)}
+	r := &tokenRefresher{provider: c, conn: conn}
+	done := make(chan struct{})
+	go func() {
+		r.run(first.Expiry)
+		close(done)
+	}()
+
+	select {
+	case <-conn.updated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("token was not refreshed")
+	}
+	conn.mu.Lock()
+	secrets := conn.secrets
+	conn.mu.Unlock()
+	issued := srv.Issued()
+	if len(secrets) != 1 || secrets[0] != issued[len(issued)-1] || secrets[0] == first.AccessToken {
+		t.Fatalf("updated secrets %q, issued %q", secrets, issued)
+	}
+
+	conn.closed <- &amqp.Error{}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("refresher kept running after the connection closed")
+	}
+}
+
+func TestRefreshWait(t *testing.T) {
+	tests := []struct {
+		left time.Duration
+		want time.Duration
+	}{
+		{time.Hour, time.Hour - refreshSkew},
+		{2 * refreshSkew, refreshSkew},
+		{refreshSkew, refreshSkew / 2},
+		{4 * time.Second, 2 * time.Second},
+		{time.Second, minRefreshWait},
+		{-time.Minute, minRefreshWait},
+	}
+	for _, tt := range tests {
+		got := refreshWait(time.Now().Add(tt.left))
+		// time passes between computing the expiry and the wait
+		if got > tt.want || got < tt.want-100*time.Millisecond {
+			t.Errorf("refreshWait(now+%v) = %v, want %v", tt.left, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshShortLivedToken(t *testing.T) {
+	srv := oauthtest.NewServer("bridge", "s3cret")
+	defer srv.Close()
+	srv.ExpiresIn = time.Second
+
+	c := &ClientCredentials{TokenURL: srv.TokenURL(), ClientID: "bridge", ClientSecret: "s3cret"}
+	first, err := c.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := &fakeConn{updated: make(chan struct{}, 100)}
+	r := &tokenRefresher{provider: c, conn: conn}
+	done := make(chan struct{})
+	go func() {
+		r.run(first.Expiry)
+		close(done)
+	}()
+
+	select {
+	case <-conn.updated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("token was not refreshed")
+	}
+	time.Sleep(1500 * time.Millisecond)
+	conn.closed <- &amqp.Error{}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("refresher kept running after the connection closed")
+	}
+	// one token at start, then at most one refresh per minRefreshWait
+	if n := len(srv.Issued()); n < 2 || n > 4 {
+		t.Fatalf("issued %v tokens in about 2.5s for tokens living 1s", n)
+	}
+}
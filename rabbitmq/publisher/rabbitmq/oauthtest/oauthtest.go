@@ -0,0 +1,118 @@
+// Package oauthtest provides a local OAuth2 token endpoint for exercising
+// token based connections without a real identity provider.
+package oauthtest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// Server is an httptest server issuing client-credentials tokens. Tokens are
+// HS256 JWTs signed with the client secret.
+type Server struct {
+	*httptest.Server
+
+	ClientID     string
+	ClientSecret string
+	// ExpiresIn is the lifetime reported for every issued token.
+	ExpiresIn time.Duration
+
+	mu     sync.Mutex
+	issued []string
+	fail   bool
+}
+
+// NewServer starts a token server accepting the given client credentials.
+func NewServer(clientID, clientSecret string) *Server {
+	s := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		ExpiresIn:    time.Hour,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// TokenURL is the endpoint to configure on a ClientCredentials provider.
+func (s *Server) TokenURL() string {
+	return s.URL + "/token"
+}
+
+// Issued returns every access token handed out so far, oldest first.
+func (s *Server) Issued() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.issued...)
+}
+
+// SetFailing makes subsequent token requests fail with a server error.
+func (s *Server) SetFailing(fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path != "/token" || r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "not_found"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "server_error"})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != s.ClientID || secret != s.ClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	now := time.Now()
+	token := s.sign(map[string]any{
+		"iss":   s.URL,
+		"sub":   s.ClientID,
+		"aud":   "rabbitmq",
+		"iat":   now.Unix(),
+		"exp":   now.Add(s.ExpiresIn).Unix(),
+		"jti":   fmt.Sprintf("%v-%v", s.ClientID, len(s.issued)+1),
+		"scope": r.PostForm.Get("scope"),
+	})
+	s.issued = append(s.issued, token)
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   int64(s.ExpiresIn / time.Second),
+		"scope":        r.PostForm.Get("scope"),
+	})
+}
+
+func (s *Server) sign(claims map[string]any) string {
+	enc := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+
+	mac := hmac.New(sha256.New, []byte(s.ClientSecret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil))
+}
@@ -21,39 +21,9 @@ func DirectExchangeNormalQueue(ctx context.Context, log *logger.Logger) error {
 	default:
 		log.Info("Starting flow for direct exchange and normal queue")
 		url := os.Getenv(utils.URL)
-		directEx := rabbitmq.Exchange{
-			Name: "DirectExchange-normalQueue",
-			Type: utils.DIRECT,
-		}
-
-		deadEx := rabbitmq.Exchange{
-			Name: "DeadExchange",
-			Type: utils.DIRECT,
-		}
-
-		dlq := rabbitmq.Queue{
-			Name:       "dead-q",
-			RoutingKey: "dq",
-		}
-
-		q1 := rabbitmq.Queue{
-			Name:       "normalQ1",
-			RoutingKey: "q1",
-		}
-		q2 := rabbitmq.Queue{
-			Name:       "normalQ2",
-			RoutingKey: "q2",
-			Args: amqp091.Table{
-				"x-dead-letter-exchange":    deadEx.Name,
-				"x-dead-letter-routing-key": dlq.RoutingKey,
-			},
-		}
-
-		rbmq := rabbitmq.Rabbitmq{
-			Exchange: directEx,
-			Q:        []rabbitmq.Queue{q1, q2},
-			Url:      url,
-		}
+		rbmq := directExchange()
+		rbmq.Url = url
+		rbmq.Credentials = rabbitmq.CredentialsFromEnv()
 
 		if err := rbmq.Init(); err != nil {
 			log.Error("error initializing the rabbitmq connection on url %v", url)
@@ -68,41 +38,8 @@ func DirectExchangeNormalQueue(ctx context.Context, log *logger.Logger) error {
 
 		defer ch.Close()
 
-		if err := directEx.CreateExchange(ch); err != nil {
-			log.Error("error creating exchange")
-			return err
-		}
-
-		if err := deadEx.CreateExchange(ch); err != nil {
-			log.Error("error creating exchange")
-			return err
-		}
-
-		for _, q := range rbmq.Q {
-			if _, err := q.CreateQueue(ch); err != nil {
-				log.Error("error creating queue")
-				return err
-			}
-		}
-
-		if _, err := dlq.CreateQueue(ch); err != nil {
-			log.Error("error creating exchange")
-			return err
-		}
-
-		if err := ch.QueueBind(
-			dlq.Name,
-			dlq.RoutingKey,
-			deadEx.Name,
-			false,
-			nil,
-		); err != nil {
-			log.Error("error binding dead queue")
-			return err
-		}
-
-		if err := rbmq.Bind(ch); err != nil {
-			log.Error("error initializing the rabbitmq connection on url %v", url)
+		if err := directTopology().Declare(ch); err != nil {
+			log.Error("error declaring the topology")
 			return err
 		}
 
@@ -136,29 +73,9 @@ func TopicExchangeQuorumQueue(ctx context.Context, log *logger.Logger) error {
 	default:
 		log.Info("Starting flow for topic exchange and normal queue")
 		url := os.Getenv(utils.URL)
-		directEx := rabbitmq.Exchange{
-			Name: "TopicExchange-normalQueue",
-			Type: utils.TOPIC,
-		}
-		q1 := rabbitmq.Queue{
-			Name:       "orderQ",
-			RoutingKey: "*.Order.*",
-			Topic:      "a.Order.b",
-			Args: amqp091.Table{
-				"x-queue-type": amqp091.QueueTypeQuorum,
-			},
-		}
-		q2 := rabbitmq.Queue{
-			Name:       "payQ",
-			RoutingKey: "pay.#",
-			Topic:      "pay.from.atm",
-		}
-
-		rbmq := rabbitmq.Rabbitmq{
-			Exchange: directEx,
-			Q:        []rabbitmq.Queue{q1, q2},
-			Url:      url,
-		}
+		rbmq := topicExchange()
+		rbmq.Url = url
+		rbmq.Credentials = rabbitmq.CredentialsFromEnv()
 
 		if err := rbmq.Init(); err != nil {
 			log.Error("error initializing the rabbitmq connection on url %v", url)
@@ -173,20 +90,8 @@ func TopicExchangeQuorumQueue(ctx context.Context, log *logger.Logger) error {
 
 		defer ch.Close()
 
-		if err := directEx.CreateExchange(ch); err != nil {
-			log.Error("error creating exchange")
-			return err
-		}
-
-		for _, q := range rbmq.Q {
-			if _, err := q.CreateQueue(ch); err != nil {
-				log.Error("error creating queue")
-				return err
-			}
-		}
-
-		if err := rbmq.Bind(ch); err != nil {
-			log.Error("error initializing the rabbitmq connection on url %v", url)
+		if err := rbmq.Topology().Declare(ch); err != nil {
+			log.Error("error declaring the topology")
 			return err
 		}
 
@@ -220,25 +125,10 @@ func FanoutExchangeNormalQueue(ctx context.Context, log *logger.Logger) error {
 	default:
 		log.Info("Starting flow for direct exchange and normal queue")
 		url := os.Getenv(utils.URL)
-		ex := rabbitmq.Exchange{
-			Name: "FanoutExchange-normalQueue",
-			Type: utils.FANOUT,
-		}
-		rk := "fanout"
-		q1 := rabbitmq.Queue{
-			Name:       "Q1",
-			RoutingKey: rk,
-		}
-		q2 := rabbitmq.Queue{
-			Name:       "Q2",
-			RoutingKey: rk,
-		}
-
-		rbmq := rabbitmq.Rabbitmq{
-			Exchange: ex,
-			Q:        []rabbitmq.Queue{q1, q2},
-			Url:      url,
-		}
+		rbmq := fanoutExchange()
+		rbmq.Url = url
+		rbmq.Credentials = rabbitmq.CredentialsFromEnv()
+		rk := rbmq.Q[0].RoutingKey
 
 		if err := rbmq.Init(); err != nil {
 			log.Error("error initializing the rabbitmq connection on url %v", url)
@@ -253,20 +143,8 @@ func FanoutExchangeNormalQueue(ctx context.Context, log *logger.Logger) error {
 
 		defer ch.Close()
 
-		if err := ex.CreateExchange(ch); err != nil {
-			log.Error("error creating exchange")
-			return err
-		}
-
-		for _, q := range rbmq.Q {
-			if _, err := q.CreateQueue(ch); err != nil {
-				log.Error("error creating queue")
-				return err
-			}
-		}
-
-		if err := rbmq.Bind(ch); err != nil {
-			log.Error("error initializing the rabbitmq connection on url %v", url)
+		if err := rbmq.Topology().Declare(ch); err != nil {
+			log.Error("error declaring the topology")
 			return err
 		}
 
@@ -299,31 +177,9 @@ func HeaderExchangeNormalQueue(ctx context.Context, log *logger.Logger) error {
 	default:
 		log.Info("Starting flow for direct exchange and normal queue")
 		url := os.Getenv(utils.URL)
-		ex := rabbitmq.Exchange{
-			Name: "HeaderExchange-normalQueue",
-			Type: utils.HEADERS,
-		}
-		q1 := rabbitmq.Queue{
-			Name: "HQ1",
-			Args: amqp091.Table{
-				"x-match": "all",
-				"a":       "b",
-				"c":       "d",
-			},
-		}
-		q2 := rabbitmq.Queue{
-			Name: "HQ2",
-			Args: amqp091.Table{
-				"x-match": "any",
-				"a":       "b",
-			},
-		}
-
-		rbmq := rabbitmq.Rabbitmq{
-			Exchange: ex,
-			Q:        []rabbitmq.Queue{q1, q2},
-			Url:      url,
-		}
+		rbmq := headersExchange()
+		rbmq.Url = url
+		rbmq.Credentials = rabbitmq.CredentialsFromEnv()
 
 		if err := rbmq.Init(); err != nil {
 			log.Error("error initializing the rabbitmq connection on url %v", url)
@@ -338,20 +194,8 @@ func HeaderExchangeNormalQueue(ctx context.Context, log *logger.Logger) error {
 
 		defer ch.Close()
 
-		if err := ex.CreateExchange(ch); err != nil {
-			log.Error("error creating exchange")
-			return err
-		}
-
-		for _, q := range rbmq.Q {
-			if _, err := q.CreateQueue(ch); err != nil {
-				log.Error("error creating queue")
-				return err
-			}
-		}
-
-		if err := rbmq.Bind(ch); err != nil {
-			log.Error("error initializing the rabbitmq connection on url %v", url)
+		if err := rbmq.Topology().Declare(ch); err != nil {
+			log.Error("error declaring the topology")
 			return err
 		}
 
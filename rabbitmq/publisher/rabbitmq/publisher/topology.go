@@ -0,0 +1,138 @@
+package publisher
+
+import (
+	"publisher/rabbitmq"
+	"publisher/utils"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Topology returns everything the publisher flows declare on the broker.
+func Topology() rabbitmq.Topology {
+	return directTopology().
+		Merge(topicExchange().Topology()).
+		Merge(fanoutExchange().Topology()).
+		Merge(headersExchange().Topology())
+}
+
+var (
+	deadEx = rabbitmq.Exchange{
+		Name: "DeadExchange",
+		Type: utils.DIRECT,
+	}
+	dlq = rabbitmq.Queue{
+		Name:       "dead-q",
+		RoutingKey: "dq",
+	}
+)
+
+// directExchange is the exchange and queues of DirectExchangeNormalQueue.
+// normalQ2 dead letters into dlq.
+func directExchange() rabbitmq.Rabbitmq {
+	return rabbitmq.Rabbitmq{
+		Exchange: rabbitmq.Exchange{
+			Name: "DirectExchange-normalQueue",
+			Type: utils.DIRECT,
+		},
+		Q: []rabbitmq.Queue{
+			{
+				Name:       "normalQ1",
+				RoutingKey: "q1",
+			},
+			{
+				Name:       "normalQ2",
+				RoutingKey: "q2",
+				Args: amqp091.Table{
+					"x-dead-letter-exchange":    deadEx.Name,
+					"x-dead-letter-routing-key": dlq.RoutingKey,
+				},
+			},
+		},
+	}
+}
+
+// directTopology is directExchange together with the dead letter exchange
+// and queue it relies on.
+func directTopology() rabbitmq.Topology {
+	dead := rabbitmq.Topology{
+		Exchanges: []rabbitmq.Exchange{deadEx},
+		Queues:    []rabbitmq.Queue{dlq},
+		Bindings: []rabbitmq.Binding{
+			{
+				Exchange:   deadEx.Name,
+				Queue:      dlq.Name,
+				RoutingKey: dlq.RoutingKey,
+			},
+		},
+	}
+	return directExchange().Topology().Merge(dead)
+}
+
+func topicExchange() rabbitmq.Rabbitmq {
+	return rabbitmq.Rabbitmq{
+		Exchange: rabbitmq.Exchange{
+			Name: "TopicExchange-normalQueue",
+			Type: utils.TOPIC,
+		},
+		Q: []rabbitmq.Queue{
+			{
+				Name:       "orderQ",
+				RoutingKey: "*.Order.*",
+				Topic:      "a.Order.b",
+				Args: amqp091.Table{
+					"x-queue-type": amqp091.QueueTypeQuorum,
+				},
+			},
+			{
+				Name:       "payQ",
+				RoutingKey: "pay.#",
+				Topic:      "pay.from.atm",
+			},
+		},
+	}
+}
+
+func fanoutExchange() rabbitmq.Rabbitmq {
+	return rabbitmq.Rabbitmq{
+		Exchange: rabbitmq.Exchange{
+			Name: "FanoutExchange-normalQueue",
+			Type: utils.FANOUT,
+		},
+		Q: []rabbitmq.Queue{
+			{
+				Name:       "Q1",
+				RoutingKey: "fanout",
+			},
+			{
+				Name:       "Q2",
+				RoutingKey: "fanout",
+			},
+		},
+	}
+}
+
+func headersExchange() rabbitmq.Rabbitmq {
+	return rabbitmq.Rabbitmq{
+		Exchange: rabbitmq.Exchange{
+			Name: "HeaderExchange-normalQueue",
+			Type: utils.HEADERS,
+		},
+		Q: []rabbitmq.Queue{
+			{
+				Name: "HQ1",
+				Args: amqp091.Table{
+					"x-match": "all",
+					"a":       "b",
+					"c":       "d",
+				},
+			},
+			{
+				Name: "HQ2",
+				Args: amqp091.Table{
+					"x-match": "any",
+					"a":       "b",
+				},
+			},
+		},
+	}
+}
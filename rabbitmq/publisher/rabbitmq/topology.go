@@ -0,0 +1,103 @@
+package rabbitmq
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type Binding struct {
+	Exchange   string
+	Queue      string
+	RoutingKey string
+	Args       amqp.Table
+}
+
+// Topology is a set of exchanges, queues and bindings declared together.
+type Topology struct {
+	Exchanges []Exchange
+	Queues    []Queue
+	Bindings  []Binding
+}
+
+// Topology describes what Init, CreateExchange, CreateQueue and Bind declare
+// for r.
+func (r Rabbitmq) Topology() Topology {
+	t := Topology{
+		Exchanges: []Exchange{r.Exchange},
+		Queues:    append([]Queue(nil), r.Q...),
+	}
+	for _, q := range r.Q {
+		t.Bindings = append(t.Bindings, Binding{
+			Exchange:   r.Exchange.Name,
+			Queue:      q.Name,
+			RoutingKey: q.RoutingKey,
+			Args:       q.Args,
+		})
+	}
+	return t
+}
+
+// Merge returns t with everything in o appended, skipping exchanges and
+// queues already present by name.
+func (t Topology) Merge(o Topology) Topology {
+	out := Topology{
+		Exchanges: append([]Exchange(nil), t.Exchanges...),
+		Queues:    append([]Queue(nil), t.Queues...),
+		Bindings:  append([]Binding(nil), t.Bindings...),
+	}
+	for _, e := range o.Exchanges {
+		if !out.hasExchange(e.Name) {
+			out.Exchanges = append(out.Exchanges, e)
+		}
+	}
+	for _, q := range o.Queues {
+		if !out.hasQueue(q.Name) {
+			out.Queues = append(out.Queues, q)
+		}
+	}
+	out.Bindings = append(out.Bindings, o.Bindings...)
+	return out
+}
+
+func (t Topology) hasExchange(name string) bool {
+	for _, e := range t.Exchanges {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Topology) hasQueue(name string) bool {
+	for _, q := range t.Queues {
+		if q.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Declare creates every exchange, queue and binding in t.
+func (t Topology) Declare(ch *amqp.Channel) error {
+	for _, e := range t.Exchanges {
+		if err := e.CreateExchange(ch); err != nil {
+			return err
+		}
+	}
+	for _, q := range t.Queues {
+		if _, err := q.CreateQueue(ch); err != nil {
+			return err
+		}
+	}
+	for _, b := range t.Bindings {
+		if err := ch.QueueBind(
+			b.Queue,
+			b.RoutingKey,
+			b.Exchange,
+			false,
+			b.Args,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
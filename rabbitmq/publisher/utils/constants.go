@@ -9,4 +9,11 @@ const (
 	HEADERS ExchangeType = "headers"
 
 	URL string = "url"
+
+	TOKEN_URL     string = "token_url"
+	CLIENT_ID     string = "client_id"
+	CLIENT_SECRET string = "client_secret"
+	SCOPES        string = "scopes"
+
+	MGMT_URL string = "mgmt_url"
 )